@@ -1,106 +1,288 @@
 package assert
 
 import (
-	"fmt"
 	"github.com/wisbery/oxyde/common"
+	"net/http"
 	"reflect"
+	"strings"
 )
 
+// Type Asserter verifies conditions and reports failures using its reporter.
+type Asserter struct {
+	reporter common.Reporter // Reporter notified about assertion failures.
+}
+
+// Function With creates asserter reporting failures using specified reporter.
+// Passing testing.TB reports assertion failures to the test being executed.
+func With(r common.Reporter) *Asserter {
+	if r == nil {
+		r = common.GetReporter()
+	}
+	return &Asserter{reporter: r}
+}
+
+// Function defaultAsserter returns asserter reporting failures using default reporter.
+func defaultAsserter() *Asserter {
+	return With(common.GetReporter())
+}
+
 func Nil(actual interface{}) {
+	a := defaultAsserter()
+	a.reporter.Helper()
+	a.Nil(actual)
+}
+
+func NotNil(actual interface{}) {
+	a := defaultAsserter()
+	a.reporter.Helper()
+	a.NotNil(actual)
+}
+
+func NilError(e error) {
+	a := defaultAsserter()
+	a.reporter.Helper()
+	a.NilError(e)
+}
+
+func NilString(actual *string) {
+	a := defaultAsserter()
+	a.reporter.Helper()
+	a.NilString(actual)
+}
+
+func NotNilString(actual *string) {
+	a := defaultAsserter()
+	a.reporter.Helper()
+	a.NotNilString(actual)
+}
+
+func True(actual bool) {
+	a := defaultAsserter()
+	a.reporter.Helper()
+	a.True(actual)
+}
+
+func False(actual bool) {
+	a := defaultAsserter()
+	a.reporter.Helper()
+	a.False(actual)
+}
+
+func NotNilId(actual *string) {
+	a := defaultAsserter()
+	a.reporter.Helper()
+	a.NotNilId(actual)
+}
+
+func EqualString(expected string, actual string) {
+	a := defaultAsserter()
+	a.reporter.Helper()
+	a.EqualString(expected, actual)
+}
+
+func EqualStringNullable(expected *string, actual *string) {
+	a := defaultAsserter()
+	a.reporter.Helper()
+	a.EqualStringNullable(expected, actual)
+}
+
+func NilInt(actual *int) {
+	a := defaultAsserter()
+	a.reporter.Helper()
+	a.NilInt(actual)
+}
+
+func EqualInt(expected int, actual int) {
+	a := defaultAsserter()
+	a.reporter.Helper()
+	a.EqualInt(expected, actual)
+}
+
+func EqualIntNullable(expected *int, actual *int) {
+	a := defaultAsserter()
+	a.reporter.Helper()
+	a.EqualIntNullable(expected, actual)
+}
+
+func EqualFloat64(expected float64, actual float64) {
+	a := defaultAsserter()
+	a.reporter.Helper()
+	a.EqualFloat64(expected, actual)
+}
+
+func EqualBool(expected bool, actual bool) {
+	a := defaultAsserter()
+	a.reporter.Helper()
+	a.EqualBool(expected, actual)
+}
+
+func EqualHeader(expected string, header http.Header, name string) {
+	a := defaultAsserter()
+	a.reporter.Helper()
+	a.EqualHeader(expected, header, name)
+}
+
+func HeaderContains(expected string, header http.Header, name string) {
+	a := defaultAsserter()
+	a.reporter.Helper()
+	a.HeaderContains(expected, header, name)
+}
+
+func EqualCookie(expected string, cookies []*http.Cookie, name string) {
+	a := defaultAsserter()
+	a.reporter.Helper()
+	a.EqualCookie(expected, cookies, name)
+}
+
+func (a *Asserter) Nil(actual interface{}) {
+	a.reporter.Helper()
 	if !common.NilValue(actual) {
-		displayAssertionError(nil, actual)
+		a.fail(nil, actual)
 	}
 }
 
-func NotNil(actual interface{}) {
-	if reflect.ValueOf(actual).IsNil() {
-		displayAssertionError("not nil", actual)
+func (a *Asserter) NotNil(actual interface{}) {
+	a.reporter.Helper()
+	if common.NilValue(actual) || isNilValue(actual) {
+		a.fail("not nil", actual)
 	}
 }
 
-func NilError(e error) {
+func (a *Asserter) NilError(e error) {
+	a.reporter.Helper()
 	if e != nil {
-		displayAssertionError(nil, e)
+		a.fail(nil, e)
 	}
 }
 
-func NilString(actual *string) {
+func (a *Asserter) NilString(actual *string) {
+	a.reporter.Helper()
 	if actual != nil {
-		displayAssertionError(nil, actual)
+		a.fail(nil, actual)
 	}
 }
 
-func NotNilString(actual *string) {
+func (a *Asserter) NotNilString(actual *string) {
+	a.reporter.Helper()
 	if actual == nil {
-		displayAssertionError("not nil", actual)
+		a.fail("not nil", actual)
 	}
 }
 
-func True(actual bool) {
+func (a *Asserter) True(actual bool) {
+	a.reporter.Helper()
 	if !actual {
-		displayAssertionError(true, actual)
+		a.fail(true, actual)
 	}
 }
 
-func False(actual bool) {
+func (a *Asserter) False(actual bool) {
+	a.reporter.Helper()
 	if actual {
-		displayAssertionError(false, actual)
+		a.fail(false, actual)
 	}
 }
 
-func NotNilId(actual *string) {
-	NotNilString(actual)
-	EqualInt(36, len(*actual))
+func (a *Asserter) NotNilId(actual *string) {
+	a.reporter.Helper()
+	if actual == nil {
+		a.fail("not nil", actual)
+		return
+	}
+	a.EqualInt(36, len(*actual))
 }
 
-func EqualString(expected string, actual string) {
+func (a *Asserter) EqualString(expected string, actual string) {
+	a.reporter.Helper()
 	if !equalString(expected, actual) {
-		displayAssertionError(expected, actual)
+		a.fail(expected, actual)
 	}
 }
 
-func EqualStringNullable(expected *string, actual *string) {
+func (a *Asserter) EqualStringNullable(expected *string, actual *string) {
+	a.reporter.Helper()
 	if !equalStringNullable(expected, actual) {
 		if expected != nil && actual != nil {
-			displayAssertionError(*expected, *actual)
+			a.fail(*expected, *actual)
+		} else {
+			a.fail(expected, actual)
 		}
-		displayAssertionError(expected, actual)
 	}
 }
 
-func NilInt(actual *int) {
+func (a *Asserter) NilInt(actual *int) {
+	a.reporter.Helper()
 	if actual != nil {
-		displayAssertionError(nil, actual)
+		a.fail(nil, actual)
 	}
 }
 
-func EqualInt(expected int, actual int) {
+func (a *Asserter) EqualInt(expected int, actual int) {
+	a.reporter.Helper()
 	if !equalInt(expected, actual) {
-		displayAssertionError(expected, actual)
+		a.fail(expected, actual)
 	}
 }
 
-func EqualIntNullable(expected *int, actual *int) {
+func (a *Asserter) EqualIntNullable(expected *int, actual *int) {
+	a.reporter.Helper()
 	if !equalIntNullable(expected, actual) {
 		if expected != nil && actual != nil {
-			displayAssertionError(*expected, *actual)
+			a.fail(*expected, *actual)
+		} else {
+			a.fail(expected, actual)
 		}
-		displayAssertionError(expected, actual)
 	}
 }
 
-func EqualFloat64(expected float64, actual float64) {
+func (a *Asserter) EqualFloat64(expected float64, actual float64) {
+	a.reporter.Helper()
 	if !equalFloat64(expected, actual) {
-		displayAssertionError(expected, actual)
+		a.fail(expected, actual)
 	}
 }
 
-func EqualBool(expected bool, actual bool) {
+func (a *Asserter) EqualBool(expected bool, actual bool) {
+	a.reporter.Helper()
 	if !equalBool(expected, actual) {
-		displayAssertionError(expected, actual)
+		a.fail(expected, actual)
+	}
+}
+
+// Function EqualHeader checks if the value of HTTP header with specified name equals to expected value.
+func (a *Asserter) EqualHeader(expected string, header http.Header, name string) {
+	a.reporter.Helper()
+	if actual, ok := header[http.CanonicalHeaderKey(name)]; !ok {
+		a.fail(name+": "+expected, "no header "+name)
+	} else if !equalString(expected, header.Get(name)) {
+		a.fail(name+": "+expected, name+": "+strings.Join(actual, ", "))
 	}
 }
 
+// Function HeaderContains checks if the value of HTTP header with specified name contains expected value.
+func (a *Asserter) HeaderContains(expected string, header http.Header, name string) {
+	a.reporter.Helper()
+	if actual, ok := header[http.CanonicalHeaderKey(name)]; !ok {
+		a.fail(name+": *"+expected+"*", "no header "+name)
+	} else if !strings.Contains(header.Get(name), expected) {
+		a.fail(name+": *"+expected+"*", name+": "+strings.Join(actual, ", "))
+	}
+}
+
+// Function EqualCookie checks if the value of the cookie with specified name equals to expected value.
+func (a *Asserter) EqualCookie(expected string, cookies []*http.Cookie, name string) {
+	a.reporter.Helper()
+	for _, cookie := range cookies {
+		if cookie.Name == name {
+			if !equalString(expected, cookie.Value) {
+				a.fail(name+"="+expected, name+"="+cookie.Value)
+			}
+			return
+		}
+	}
+	a.fail(name+"="+expected, "no cookie "+name)
+}
+
 // Function equalString checks if two string values are equal.
 func equalString(expected string, actual string) bool {
 	return expected == actual
@@ -143,13 +325,18 @@ func equalBool(expected bool, actual bool) bool {
 	return expected == actual
 }
 
-// Function displayAssertionError displays assertion error details.
-func displayAssertionError(expected interface{}, actual interface{}) {
-	separator := common.MakeString('-', 120)
-	fmt.Printf("\n\n%s\n>     ERROR: assertion error\n>  Expected: %+v\n>    Actual: %+v\n%s\n\n",
-		separator,
-		expected,
-		actual,
-		separator)
-	common.BrExit()
+// Function isNilValue checks if the value of nillable kind is nil.
+func isNilValue(value interface{}) bool {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
+
+// Function fail reports assertion error details.
+func (a *Asserter) fail(expected interface{}, actual interface{}) {
+	a.reporter.Helper()
+	common.Fail(a.reporter, "assertion error", expected, actual)
 }
@@ -1,6 +1,13 @@
 package assert
 
-import "testing"
+import (
+	"fmt"
+	"github.com/wisbery/oxyde/common"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+)
 
 func TestEqualStrings(t *testing.T) {
 	if !equalString("string", "string") {
@@ -41,3 +48,78 @@ func TestEqualStringsNullable(t *testing.T) {
 		t.Error("strings are not equal but test shows they are")
 	}
 }
+
+type testReporter struct {
+	messages []string
+	failed   bool
+	helpers  []string
+}
+
+func (r *testReporter) Helper() {
+	if pc, _, _, ok := runtime.Caller(1); ok {
+		r.helpers = append(r.helpers, runtime.FuncForPC(pc).Name())
+	}
+}
+
+func (r *testReporter) Errorf(format string, args ...interface{}) {
+	r.messages = append(r.messages, fmt.Sprintf(format, args...))
+}
+
+func (r *testReporter) FailNow() {
+	r.failed = true
+}
+
+func TestAsserterReportsFailure(t *testing.T) {
+	reporter := &testReporter{}
+	With(reporter).EqualString("Alfa", "Alfb")
+	if !reporter.failed || len(reporter.messages) != 1 {
+		t.Fatalf("expected single reported failure, got: %+v", reporter)
+	}
+	if !strings.Contains(reporter.messages[0], "Alfa") || !strings.Contains(reporter.messages[0], "Alfb") {
+		t.Errorf("unexpected failure message: %s", reporter.messages[0])
+	}
+}
+
+func TestAsserterReportsNilId(t *testing.T) {
+	reporter := &testReporter{}
+	With(reporter).NotNilId(nil)
+	if !reporter.failed || len(reporter.messages) != 1 {
+		t.Errorf("expected single reported failure, got: %+v", reporter)
+	}
+}
+
+func TestDefaultReporterHelper(t *testing.T) {
+	reporter := &testReporter{}
+	defer common.SetReporter(common.GetReporter())
+	common.SetReporter(reporter)
+	EqualString("Alfa", "Alfb")
+	if !reporter.failed || len(reporter.helpers) == 0 || !strings.HasSuffix(reporter.helpers[0], "assert.EqualString") {
+		t.Errorf("expected package function marked as helper, got: %v", reporter.helpers)
+	}
+}
+
+func TestAsserterWithTestingT(t *testing.T) {
+	a := With(t)
+	a.EqualString("string", "string")
+	a.EqualInt(1, 1)
+	a.True(true)
+	a.NotNil(&t)
+	a.Nil(nil)
+}
+
+func TestHeaderAssertions(t *testing.T) {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	header.Set("Location", "/users/1")
+	cookies := []*http.Cookie{{Name: "session", Value: "s-1"}}
+	a := With(t)
+	a.EqualHeader("/users/1", header, "location")
+	a.HeaderContains("application/json", header, "Content-Type")
+	a.EqualCookie("s-1", cookies, "session")
+	reporter := &testReporter{}
+	With(reporter).EqualHeader("no-cache", header, "Cache-Control")
+	With(reporter).EqualCookie("s-2", cookies, "session")
+	if len(reporter.messages) != 2 || !strings.Contains(reporter.messages[0], "no header Cache-Control") || !strings.Contains(reporter.messages[1], "session=s-1") {
+		t.Errorf("unexpected failure messages: %v", reporter.messages)
+	}
+}
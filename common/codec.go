@@ -0,0 +1,120 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"mime"
+	"strings"
+	"sync"
+)
+
+// Interface for codecs encoding and decoding request and response bodies of specific media type.
+// Codecs for JSON and XML are registered by default, codecs for other media types
+// (like YAML, CBOR or MessagePack) may be registered using RegisterCodec function.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)      // Encodes the value.
+	Unmarshal(data []byte, v interface{}) error // Decodes the data into the value.
+	Indent(data []byte) string                  // Returns pretty-printed data, displayed in examples.
+}
+
+var (
+	codecsMutex sync.RWMutex
+	codecs      = map[string]Codec{
+		"application/json": JsonCodec{},
+		"application/xml":  XmlCodec{},
+		"text/xml":         XmlCodec{}}
+)
+
+// Function RegisterCodec registers the codec for specified media type,
+// replacing the codec registered earlier for the same media type.
+func RegisterCodec(mediaType string, codec Codec) {
+	codecsMutex.Lock()
+	defer codecsMutex.Unlock()
+	codecs[strings.ToLower(mediaType)] = codec
+}
+
+// Function LookupCodec returns the codec for specified media type or 'Content-Type' header value.
+// Parameters (like charset) are ignored. Media types with structured syntax suffix,
+// like 'application/problem+json', use the codec registered for the suffix.
+func LookupCodec(contentType string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	codecsMutex.RLock()
+	defer codecsMutex.RUnlock()
+	if codec, ok := codecs[mediaType]; ok {
+		return codec, true
+	}
+	if i := strings.LastIndex(mediaType, "+"); i >= 0 {
+		if codec, ok := codecs["application/"+mediaType[i+1:]]; ok {
+			return codec, true
+		}
+	}
+	return nil, false
+}
+
+// Function PrettyPrintAs returns data pretty-printed using the codec for specified media type.
+// When no codec is registered for specified media type, data is pretty-printed as JSON.
+func PrettyPrintAs(contentType string, in []byte) string {
+	if codec, ok := LookupCodec(contentType); ok {
+		return codec.Indent(in)
+	}
+	return PrettyPrint(in)
+}
+
+// Type JsonCodec encodes and decodes JSON bodies.
+type JsonCodec struct{}
+
+func (JsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (JsonCodec) Indent(data []byte) string {
+	return PrettyPrint(data)
+}
+
+// Type XmlCodec encodes and decodes XML bodies.
+type XmlCodec struct{}
+
+func (XmlCodec) Marshal(v interface{}) ([]byte, error) {
+	return xml.Marshal(v)
+}
+
+func (XmlCodec) Unmarshal(data []byte, v interface{}) error {
+	return xml.Unmarshal(data, v)
+}
+
+func (XmlCodec) Indent(data []byte) string {
+	var out bytes.Buffer
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	encoder := xml.NewEncoder(&out)
+	encoder.Indent("", "  ")
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return string(data)
+		}
+		if charData, ok := token.(xml.CharData); ok {
+			if len(bytes.TrimSpace(charData)) == 0 {
+				continue
+			}
+		}
+		if err = encoder.EncodeToken(token); err != nil {
+			return string(data)
+		}
+	}
+	if err := encoder.Flush(); err != nil {
+		return string(data)
+	}
+	return out.String()
+}
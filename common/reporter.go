@@ -0,0 +1,60 @@
+package common
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Interface for reporting failures detected while executing tests.
+// This interface is satisfied by testing.TB, so failures may be reported
+// directly to Go tests, subtests, IDEs and CI tools.
+type Reporter interface {
+	Helper()                                   // Marks the calling function as a helper function.
+	Errorf(format string, args ...interface{}) // Reports the failure message.
+	FailNow()                                  // Stops the execution of the current test.
+}
+
+// Type ExitReporter reports failures by displaying the failure message between
+// separator lines, followed by stack trace. Then the execution is stopped with exit code 1.
+type ExitReporter struct{}
+
+func (ExitReporter) Helper() {}
+
+func (ExitReporter) Errorf(format string, args ...interface{}) {
+	separator := MakeString('-', 120)
+	fmt.Printf("\n\n%s\n%s\n%s\n\n", separator, fmt.Sprintf(format, args...), separator)
+}
+
+func (ExitReporter) FailNow() {
+	BrExit()
+}
+
+var (
+	reporterMutex   sync.RWMutex
+	defaultReporter Reporter = ExitReporter{}
+)
+
+// Function SetReporter sets the default reporter used when no other reporter is specified.
+// Passing nil restores ExitReporter.
+func SetReporter(r Reporter) {
+	reporterMutex.Lock()
+	defer reporterMutex.Unlock()
+	if r == nil {
+		r = ExitReporter{}
+	}
+	defaultReporter = r
+}
+
+// Function GetReporter returns the default reporter.
+func GetReporter() Reporter {
+	reporterMutex.RLock()
+	defer reporterMutex.RUnlock()
+	return defaultReporter
+}
+
+// Function Fail reports the failure with expected and actual values and stops the execution.
+func Fail(r Reporter, title string, expected interface{}, actual interface{}) {
+	r.Helper()
+	r.Errorf(">     ERROR: %s\n>  Expected: %+v\n>    Actual: %+v", title, expected, actual)
+	r.FailNow()
+}
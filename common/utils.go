@@ -10,14 +10,25 @@ import (
 	"reflect"
 	"regexp"
 	"runtime"
+	"strings"
 )
 
 const (
-	ApiTagName     = "api"  // Name of the tag in which documentation details are stored.
-	JsonTagName    = "json" // Name of the tag in which JSON details are stored.
-	OptionalPrefix = "?"    // Prefix used to mark th field as optional.
+	ApiTagName     = "api"   // Name of the tag in which documentation details are stored.
+	JsonTagName    = "json"  // Name of the tag in which JSON details are stored.
+	FormTagName    = "form"  // Name of the tag in which form field details are stored.
+	XmlTagName     = "xml"   // Name of the tag in which XML details are stored.
+	QueryTagName   = "query" // Name of the tag in which request parameter details are stored.
+	OptionalPrefix = "?"     // Prefix used to mark th field as optional.
 )
 
+// Type File represents the file sent as a part of multipart request body.
+type File struct {
+	Name        string // Name of the file.
+	ContentType string // Media type of the file content, 'application/octet-stream' when empty.
+	Content     []byte // File content.
+}
+
 // Function MakeString creates a string of length 'len' containing the same character 'ch'.
 func MakeString(ch byte, len int) string {
 	b := make([]byte, len)
@@ -32,6 +43,13 @@ func NilValue(value interface{}) bool {
 	return value == nil || (reflect.ValueOf(value).Kind() == reflect.Ptr && reflect.ValueOf(value).IsNil())
 }
 
+// Function TagName returns the name stored in specified tag of the struct field,
+// without options following the name (like 'omitempty').
+func TagName(field reflect.StructField, tagName string) string {
+	name, _, _ := strings.Cut(field.Tag.Get(tagName), ",")
+	return name
+}
+
 // Function PrettyPrint takes JSON string as an argument
 // and returns the same JSON but pretty-printed.
 func PrettyPrint(in []byte) string {
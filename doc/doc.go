@@ -1,11 +1,16 @@
 package doc
 
 import (
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"github.com/wisbery/oxyde/common"
+	"net/http"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 const (
@@ -22,40 +27,71 @@ const (
 	AccessError
 )
 
+var (
+	fileType    = reflect.TypeOf(common.File{})
+	timeType    = reflect.TypeOf(time.Time{})
+	xmlNameType = reflect.TypeOf(xml.Name{})
+)
+
 type RoleKey struct {
 	method   string
 	path     string
 	roleName string
 }
 
+// Type Context collects the documentation of endpoints. The state of currently documented
+// endpoint (collecting mode, example summary, role name) belongs to a single collection scope
+// and must not be shared between goroutines. Use NewScope to create a separate scope
+// for every test or goroutine, all scopes save the documentation in the same shared registry.
 type Context struct {
-	mode               int             // Documentation collecting data mode.
-	exampleSummary     string          // Summary for the next collected example.
-	exampleDescription string          // Detailed description for the next collected example.
-	roleName           string          // Role name of the principal for the next endpoint example.
-	endpoint           *Endpoint       // Currently documented endpoint data.
-	endpoints          []Endpoint      // List of documented endpoints.
-	roleNames          []string        // Role names in order they should be displayed.
-	roles              map[RoleKey]int // Map of access roles tested for endpoints.
+	mode               int       // Documentation collecting data mode.
+	exampleSummary     string    // Summary for the next collected example.
+	exampleDescription string    // Detailed description for the next collected example.
+	roleName           string    // Role name of the principal for the next endpoint example.
+	endpoint           *Endpoint // Currently documented endpoint data.
+	registry           *registry // Documentation shared by all scopes.
+}
+
+// Type registry holds the documentation shared by all collection scopes.
+type registry struct {
+	mutex     sync.RWMutex    // Guards the documentation against concurrent access.
+	endpoints []Endpoint      // List of documented endpoints.
+	roleNames []string        // Role names in order they should be displayed.
+	roles     map[RoleKey]int // Map of access roles tested for endpoints.
+	reports   []LoadReport    // List of load test reports.
 }
 
 func CreateDocContext() *Context {
 	return &Context{
-		mode:      CollectNone,
-		endpoint:  nil,
-		endpoints: make([]Endpoint, 0),
-		roles:     make(map[RoleKey]int)}
+		mode:     CollectNone,
+		endpoint: nil,
+		registry: &registry{
+			endpoints: make([]Endpoint, 0),
+			roles:     make(map[RoleKey]int)}}
+}
+
+// Function NewScope creates new collection scope saving documentation in the same
+// shared registry as this context. Each test running in parallel (or each goroutine)
+// should collect documentation using its own scope.
+func (dc *Context) NewScope() *Context {
+	return &Context{
+		mode:     CollectNone,
+		endpoint: nil,
+		registry: dc.registry}
 }
 
 func (dc *Context) ClearDocumentation() {
 	dc.mode = CollectNone
 	dc.endpoint = nil
-	dc.endpoints = make([]Endpoint, 0)
-	dc.roles = make(map[RoleKey]int)
+	dc.registry.mutex.Lock()
+	defer dc.registry.mutex.Unlock()
+	dc.registry.endpoints = make([]Endpoint, 0)
+	dc.registry.roles = make(map[RoleKey]int)
+	dc.registry.reports = nil
 }
 
 func (dc *Context) PublishDocumentation() {
-	for _, endpoint := range dc.endpoints {
+	for _, endpoint := range dc.GetEndpoints() {
 		PrintEndpoint(endpoint)
 	}
 }
@@ -112,25 +148,31 @@ func (dc *Context) StopCollecting() {
 }
 
 func (dc *Context) SetRolesOrder(roleOrder []string) {
-	dc.roleNames = roleOrder
+	dc.registry.mutex.Lock()
+	defer dc.registry.mutex.Unlock()
+	dc.registry.roleNames = roleOrder
 }
 
 func (dc *Context) SaveRole(method string, path string, status int) {
 	if dc.roleName != "" {
 		key := RoleKey{method: method, path: path, roleName: dc.roleName}
+		dc.registry.mutex.Lock()
+		defer dc.registry.mutex.Unlock()
 		switch status {
 		case 200:
-			dc.roles[key] = AccessGranted
+			dc.registry.roles[key] = AccessGranted
 		case 401:
-			dc.roles[key] = AccessDenied
+			dc.registry.roles[key] = AccessDenied
 		default:
-			dc.roles[key] = AccessError
+			dc.registry.roles[key] = AccessError
 		}
 	}
 }
 
 func (dc *Context) GetRoleNames() []string {
-	return dc.roleNames
+	dc.registry.mutex.RLock()
+	defer dc.registry.mutex.RUnlock()
+	return dc.registry.roleNames
 }
 
 func (dc *Context) GetAccess(method string, path string, roleName string) int {
@@ -138,7 +180,9 @@ func (dc *Context) GetAccess(method string, path string, roleName string) int {
 		method:   method,
 		path:     path,
 		roleName: roleName}
-	if access, ok := dc.roles[key]; ok {
+	dc.registry.mutex.RLock()
+	defer dc.registry.mutex.RUnlock()
+	if access, ok := dc.registry.roles[key]; ok {
 		return access
 	} else {
 		return AccessUnknown
@@ -147,12 +191,30 @@ func (dc *Context) GetAccess(method string, path string, roleName string) int {
 
 func (dc *Context) SaveEndpointDocumentation() {
 	if dc.endpoint != nil {
-		dc.endpoints = append(dc.endpoints, *dc.endpoint)
+		dc.registry.mutex.Lock()
+		defer dc.registry.mutex.Unlock()
+		dc.registry.endpoints = append(dc.registry.endpoints, *dc.endpoint)
 	}
 }
 
+// Function GetEndpoints returns a copy of the list of endpoints documented in all scopes.
 func (dc *Context) GetEndpoints() []Endpoint {
-	return dc.endpoints
+	dc.registry.mutex.RLock()
+	defer dc.registry.mutex.RUnlock()
+	return append(make([]Endpoint, 0, len(dc.registry.endpoints)), dc.registry.endpoints...)
+}
+
+func (dc *Context) SaveLoadReport(report LoadReport) {
+	dc.registry.mutex.Lock()
+	defer dc.registry.mutex.Unlock()
+	dc.registry.reports = append(dc.registry.reports, report)
+}
+
+// Function GetLoadReports returns a copy of the list of saved load test reports.
+func (dc *Context) GetLoadReports() []LoadReport {
+	dc.registry.mutex.RLock()
+	defer dc.registry.mutex.RUnlock()
+	return append(make([]LoadReport, 0, len(dc.registry.reports)), dc.registry.reports...)
 }
 
 func (dc *Context) GetExampleSummary() string {
@@ -164,16 +226,17 @@ func (dc *Context) GetExampleDescription() string {
 }
 
 type Endpoint struct {
-	Id           string    // Unique endpoint identifier.
-	Tags         []string  // List of tags of endpoint.
-	Method       string    // HTTP method name, like GET, POST, PUT or DELETE.
-	UrlRoot      string    // Request URL root.
-	UrlPath      string    // Request URL path after root.
-	Summary      string    // Summary text describing endpoint.
-	Parameters   []Field   // Description of request parameters.
-	RequestBody  []Field   // Description of request body.
-	ResponseBody []Field   // Description of results.
-	Examples     []Example // Description of usage examples.
+	Id               string    // Unique endpoint identifier.
+	Tags             []string  // List of tags of endpoint.
+	Method           string    // HTTP method name, like GET, POST, PUT, PATCH or DELETE.
+	UrlRoot          string    // Request URL root.
+	UrlPath          string    // Request URL path after root.
+	Summary          string    // Summary text describing endpoint.
+	Parameters       []Field   // Description of request parameters.
+	RequestBody      []Field   // Description of request body.
+	RequestMediaType string    // Media type of request body, like application/json.
+	ResponseBody     []Field   // Description of results.
+	Examples         []Example // Description of usage examples.
 }
 
 func (e *Endpoint) AddTag(tag string) {
@@ -193,7 +256,16 @@ type Field struct {
 
 func CreateField(typ reflect.Type, structField reflect.StructField) Field {
 	jsonType := jsonType(typ)
-	jsonName := structField.Tag.Get(common.JsonTagName)
+	jsonName := common.TagName(structField, common.JsonTagName)
+	if jsonName == "" {
+		jsonName = common.TagName(structField, common.FormTagName)
+	}
+	if jsonName == "" {
+		jsonName = common.TagName(structField, common.XmlTagName)
+	}
+	if jsonName == "" {
+		jsonName = common.TagName(structField, common.QueryTagName)
+	}
 	apiTagContent := structField.Tag.Get(common.ApiTagName)
 	mandatory := !strings.HasPrefix(apiTagContent, common.OptionalPrefix)
 	apiTagContent = strings.TrimPrefix(apiTagContent, common.OptionalPrefix)
@@ -206,13 +278,57 @@ func CreateField(typ reflect.Type, structField reflect.StructField) Field {
 }
 
 type Example struct {
-	Summary      string // Example summary.
-	Description  string // Detailed example description.
-	Method       string // HTTP method name.
-	Uri          string // Request URI.
-	StatusCode   int    // HTTP status code.
-	RequestBody  string // Request body as JSON string.
-	ResponseBody string // Response body as JSON string.
+	Summary         string      // Example summary.
+	Description     string      // Detailed example description.
+	Method          string      // HTTP method name.
+	Uri             string      // Request URI.
+	StatusCode      int         // HTTP status code.
+	ResponseHeaders http.Header // HTTP response headers.
+	Timing          Timing      // Measured durations of request phases.
+	RequestHeaders  http.Header // HTTP request headers actually sent, with secrets redacted.
+	Curl            string      // Curl command executing the request.
+	RequestBody     string      // Request body as JSON string.
+	ResponseBody    string      // Response body as JSON string.
+}
+
+type Timing struct {
+	DNS     time.Duration `json:"dns"`     // Duration of DNS lookup, zero when no lookup was done.
+	Connect time.Duration `json:"connect"` // Duration of establishing TCP connection, zero when connection was reused.
+	TLS     time.Duration `json:"tls"`     // Duration of TLS handshake, zero for plain HTTP or reused connection.
+	TTFB    time.Duration `json:"ttfb"`    // Time from sending the request until the first byte of response was received.
+	Total   time.Duration `json:"total"`   // Time from sending the request until the response body was read.
+}
+
+// Function String returns the measured durations in readable form.
+func (t Timing) String() string {
+	return fmt.Sprintf("total: %v (dns: %v, connect: %v, tls: %v, ttfb: %v)",
+		t.Total.Round(10*time.Microsecond),
+		t.DNS.Round(10*time.Microsecond),
+		t.Connect.Round(10*time.Microsecond),
+		t.TLS.Round(10*time.Microsecond),
+		t.TTFB.Round(10*time.Microsecond))
+}
+
+type LoadReport struct {
+	Name        string        `json:"name"`        // Name of the load test.
+	Concurrency int           `json:"concurrency"` // Number of concurrently running scenarios.
+	Duration    time.Duration `json:"duration"`    // Total duration of the load test.
+	Iterations  int           `json:"iterations"`  // Number of executed scenarios.
+	Failures    int           `json:"failures"`    // Number of failed scenarios.
+	Endpoints   []LoadStats   `json:"endpoints"`   // Statistics of requests grouped by endpoint.
+}
+
+type LoadStats struct {
+	Method     string        `json:"method"`     // HTTP method name.
+	UrlPath    string        `json:"urlPath"`    // Request URL path after root.
+	Requests   int           `json:"requests"`   // Number of executed requests.
+	Errors     int           `json:"errors"`     // Number of failed requests.
+	ErrorRate  float64       `json:"errorRate"`  // Ratio of failed requests to all requests.
+	Throughput float64       `json:"throughput"` // Number of requests per second.
+	P50        time.Duration `json:"p50"`        // Median latency.
+	P90        time.Duration `json:"p90"`        // 90th percentile of latency.
+	P99        time.Duration `json:"p99"`        // 99th percentile of latency.
+	Max        time.Duration `json:"max"`        // Maximum latency.
 }
 
 func ParseObject(o interface{}) []Field {
@@ -225,10 +341,16 @@ func ParseFields(typ reflect.Type) []Field {
 	case reflect.Ptr:
 		return ParseFields(typ.Elem())
 	case reflect.Struct:
+		if typ == fileType || typ == timeType {
+			return []Field{}
+		}
 		fields := make([]Field, 0)
 		for i := 0; i < typ.NumField(); i++ {
 			childField := typ.Field(i)
 			childType := childField.Type
+			if childType == xmlNameType {
+				continue
+			}
 			field := CreateField(childType, childField)
 			switch field.JsonType {
 			case "object":
@@ -248,6 +370,12 @@ func jsonType(typ reflect.Type) string {
 	case reflect.Ptr:
 		return jsonType(typ.Elem())
 	case reflect.Struct:
+		if typ == fileType {
+			return "binary"
+		}
+		if typ == timeType {
+			return "string"
+		}
 		return "object"
 	case reflect.Slice:
 		return "array"
@@ -306,6 +434,95 @@ func PrintFields(fields []Field, indent string, level int) {
 func PrintExample(usage Example) {
 	fmt.Printf("\nExample:\n")
 	fmt.Printf("%d %s %s\n", usage.StatusCode, usage.Method, usage.Uri)
+	if usage.Timing.Total > 0 {
+		fmt.Printf("Latency: %s\n", usage.Timing)
+	}
+	if usage.Curl != "" {
+		fmt.Printf("Curl:\n%s\n", usage.Curl)
+	}
+	if len(usage.ResponseHeaders) > 0 {
+		fmt.Printf("ResponseHeaders:\n%s", FormatHeaders(usage.ResponseHeaders))
+	}
 	fmt.Printf("Parameters:\n%s\n", usage.RequestBody)
 	fmt.Printf("ResponseBody:\n%s\n", usage.ResponseBody)
 }
+
+// Function FormatHeaders returns HTTP headers formatted as 'Name: value' lines, sorted by name.
+func FormatHeaders(header http.Header) string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		for _, value := range header[name] {
+			b.WriteString(name + ": " + value + "\n")
+		}
+	}
+	return b.String()
+}
+
+// Function CurlCommand returns curl command executing HTTP request with specified method, URI and headers.
+// The request body is passed either as raw data or, for multipart requests, as form fields
+// in curl syntax, like 'name=value' or 'avatar=@me.png;type=image/png'.
+func CurlCommand(method string, uri string, header http.Header, body string, form []string) string {
+	args := []string{"curl"}
+	if method == "HEAD" {
+		// curl -X HEAD waits for the response body that never comes
+		args = append(args, "--head")
+	} else if method != "GET" || body != "" || len(form) > 0 {
+		args = append(args, "-X", method)
+	}
+	args = append(args, shellQuote(uri))
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "Content-Length" || (len(form) > 0 && name == "Content-Type") {
+			continue
+		}
+		for _, value := range header[name] {
+			args = append(args, "-H", shellQuote(name+": "+value))
+		}
+	}
+	for _, field := range form {
+		args = append(args, "-F", shellQuote(field))
+	}
+	if body != "" {
+		args = append(args, "--data-raw", shellQuote(body))
+	}
+	return strings.Join(args, " ")
+}
+
+// Function shellQuote returns the text quoted for use as a single argument in shell command.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func PrintLoadReport(report LoadReport) {
+	fmt.Printf("\n\nLoad test: %s\n", report.Name)
+	fmt.Printf("Concurrency: %d, duration: %v, iterations: %d, failures: %d\n\n",
+		report.Concurrency,
+		report.Duration,
+		report.Iterations,
+		report.Failures)
+	fmt.Printf("%-8s %-40s %10s %8s %8s %10s %10s %10s %10s %10s\n",
+		"Method", "Path", "Requests", "Errors", "Err%", "Req/s", "p50", "p90", "p99", "Max")
+	fmt.Println(common.MakeString('-', 132))
+	for _, stats := range report.Endpoints {
+		fmt.Printf("%-8s %-40s %10d %8d %8.2f %10.2f %10v %10v %10v %10v\n",
+			stats.Method,
+			stats.UrlPath,
+			stats.Requests,
+			stats.Errors,
+			stats.ErrorRate*100,
+			stats.Throughput,
+			stats.P50,
+			stats.P90,
+			stats.P99,
+			stats.Max)
+	}
+}
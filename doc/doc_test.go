@@ -3,8 +3,10 @@ package doc
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"reflect"
 	"testing"
+	"time"
 )
 
 type TestLoginParams struct {
@@ -113,6 +115,20 @@ func TestSimpleTypes(t *testing.T) {
 	fmt.Println()
 }
 
+func TestTimeField(t *testing.T) {
+	type Data struct {
+		Created  time.Time  `json:"created" api:"Creation time."`
+		Modified *time.Time `json:"modified" api:"?Modification time."`
+	}
+	fields := ParseObject(Data{})
+	if len(fields) != 2 || fields[0].JsonType != "string" || fields[1].JsonType != "string" {
+		t.Errorf("expected time fields documented as strings: %+v", fields)
+	}
+	if len(fields[0].Children) != 0 || len(fields[1].Children) != 0 {
+		t.Errorf("expected time fields without children: %+v", fields)
+	}
+}
+
 func TestStructures(t *testing.T) {
 	type Address struct {
 		Country string `json:"country" api:"Country name."`
@@ -137,3 +153,45 @@ func TestStructures(t *testing.T) {
 	PrintFields(fields, "   ", 0)
 	fmt.Println()
 }
+
+func TestScopesShareDocumentation(t *testing.T) {
+	dc := CreateDocContext()
+	dc.SetRolesOrder([]string{"admin"})
+	t.Run("scopes", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			id := fmt.Sprintf("endpoint-%d", i)
+			t.Run(id, func(t *testing.T) {
+				t.Parallel()
+				scope := dc.NewScope()
+				scope.NewEndpointDocumentation(id, "users", "Summary of "+id)
+				scope.CollectRole("admin")
+				scope.CollectExamples("Example of "+id, "")
+				endpoint := scope.GetEndpoint()
+				endpoint.Method = "GET"
+				endpoint.UrlPath = "/" + id
+				endpoint.Examples = append(endpoint.Examples, Example{Summary: scope.GetExampleSummary()})
+				scope.SaveRole("GET", "/"+id, 200)
+				scope.StopCollecting()
+				scope.SaveEndpointDocumentation()
+			})
+		}
+	})
+	endpoints := dc.GetEndpoints()
+	if len(endpoints) != 20 {
+		t.Fatalf("expected 20 documented endpoints, got: %d", len(endpoints))
+	}
+	for _, endpoint := range endpoints {
+		if endpoint.Examples[0].Summary != "Example of "+endpoint.Id || dc.GetAccess("GET", "/"+endpoint.Id, "admin") != AccessGranted {
+			t.Errorf("documentation of endpoint %s mixed with other scopes: %+v", endpoint.Id, endpoint)
+		}
+	}
+}
+
+func TestCurlCommandHead(t *testing.T) {
+	header := http.Header{}
+	header.Set("Accept", "application/json")
+	command := CurlCommand("HEAD", "http://localhost/users/1", header, "", nil)
+	if command != "curl --head 'http://localhost/users/1' -H 'Accept: application/json'" {
+		t.Errorf("unexpected curl command: %s", command)
+	}
+}
@@ -32,7 +32,7 @@ const EndpointTemplate = `
   {{end}}
 </div>
 
-<div class="fields-container-title">Request body</div>
+<div class="fields-container-title">Request body{{if .RequestMediaType}} <span class="media-type">{{.RequestMediaType}}</span>{{end}}</div>
 <div class="parameters-description">
   {{if .RequestBody}}
     <table>
@@ -101,12 +101,21 @@ const EndpointTemplate = `
       {{if .RequestBody}}
         <div class="example-request-body"><pre>{{.RequestBody}}</pre></div>
       {{end}}
+      {{if .Curl}}
+        <div class="example-curl"><pre>{{.Curl}}</pre></div>
+      {{end}}
       <div class="example-response">
         <div class="http-status http-status-{{.StatusCode}}">{{.StatusCode}}</div>
+        {{if .Latency}}
+          <div class="example-latency">{{.Latency}}</div>
+        {{end}}
         {{if .ResponseBody}}
           <div class="example-response-body"><pre>{{.ResponseBody}}</pre></div>
         {{end}}
       </div>
+      {{if .ResponseHeaders}}
+        <div class="example-response-headers"><pre>{{.ResponseHeaders}}</pre></div>
+      {{end}}
     </div>
   </div>
 {{end}}
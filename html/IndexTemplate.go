@@ -1,6 +1,9 @@
 package html
 
 const IndexTemplate = `
+{{if .LoadReports}}
+  <div class="group-name"><a href="/load-reports">LOAD REPORTS</a></div>
+{{end}}
 {{range .Groups}}
   <div class="group-name">{{.Name}}</div>
   <div class="group-container">
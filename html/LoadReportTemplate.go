@@ -0,0 +1,49 @@
+package html
+
+const LoadReportTemplate = `
+{{range .LoadReports}}
+  <div class="group-name">{{.Name}}</div>
+  <div class="load-report-summary">
+    Concurrency: <b>{{.Concurrency}}</b>,
+    duration: <b>{{.Duration}}</b>,
+    iterations: <b>{{.Iterations}}</b>,
+    failures: <b>{{.Failures}}</b>
+  </div>
+  <div class="parameters-description">
+    <table>
+      <thead>
+        <tr>
+          <th>Method</th>
+          <th>Path</th>
+          <th>Requests</th>
+          <th>Errors</th>
+          <th>Error rate</th>
+          <th>Req/s</th>
+          <th>p50</th>
+          <th>p90</th>
+          <th>p99</th>
+          <th>Max</th>
+        </tr>
+      </thead>
+      <tbody>
+        {{range .Endpoints}}
+          <tr>
+            <td class="endpoint-summary-method http-method-{{.MethodLo}}">{{.MethodUp}}</td>
+            <td>{{.UrlPath}}</td>
+            <td class="load-number">{{.Requests}}</td>
+            <td class="load-number">{{.Errors}}</td>
+            <td class="load-number">{{.ErrorRate}}</td>
+            <td class="load-number">{{.Throughput}}</td>
+            <td class="load-number">{{.P50}}</td>
+            <td class="load-number">{{.P90}}</td>
+            <td class="load-number">{{.P99}}</td>
+            <td class="load-number">{{.Max}}</td>
+          </tr>
+        {{end}}
+      </tbody>
+    </table>
+  </div>
+{{else}}
+  <div>(none)</div>
+{{end}}
+`
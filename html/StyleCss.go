@@ -117,6 +117,13 @@ pre {
   margin: 10px 0 8px 0;
 }
 
+.media-type {
+  font-family: 'Roboto Mono', monospace;
+  font-size: 0.7em;
+  font-weight: normal;
+  color: gray;
+}
+
 .http-method-get {
   color: blue;
 }
@@ -153,6 +160,42 @@ pre {
   background-color: red;
 }
 
+.http-method-patch {
+  color: purple;
+}
+
+.details-http-method-patch {
+  color: white;
+  background-color: purple;
+}
+
+.http-method-head {
+  color: teal;
+}
+
+.details-http-method-head {
+  color: white;
+  background-color: teal;
+}
+
+.http-method-options {
+  color: gray;
+}
+
+.details-http-method-options {
+  color: white;
+  background-color: gray;
+}
+
+.http-method-ws {
+  color: sienna;
+}
+
+.details-http-method-ws {
+  color: white;
+  background-color: sienna;
+}
+
 .http-status {
   font-weight: bold;
   border-radius: 6px;
@@ -259,6 +302,12 @@ pre {
   margin-left: 94px;
 }
 
+.example-curl {
+  margin: 8px 0 0 94px;
+  font-size: 0.8em;
+  word-break: break-all;
+}
+
 .example-response {
   margin: 4px 0 8px 0;
   display: flex;
@@ -271,6 +320,26 @@ pre {
   margin-left: 4px;
 }
 
+.example-latency {
+  font-size: 0.8em;
+  color: gray;
+  margin: 6px 4px 0 4px;
+  white-space: nowrap;
+}
+
+.example-response-headers {
+  margin: 0 0 8px 94px;
+}
+
+.load-report-summary {
+  margin-bottom: 8px;
+}
+
+.load-number {
+  text-align: right;
+  font-family: 'Roboto Mono', monospace;
+}
+
 .access-YES {
   color: white;
   background-color: green;
@@ -0,0 +1,187 @@
+package load
+
+import (
+	"fmt"
+	d "github.com/wisbery/oxyde/doc"
+	"github.com/wisbery/oxyde/rest"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Type Scenario executes requests of a single load test iteration. Scenarios written
+// for functional tests may be reused, failures reported by rest functions do not stop
+// the load test, but are counted as failed iterations. Documentation is not collected.
+type Scenario func(c rest.Context, dc *d.Context) error
+
+// Type Options defines how many times and how concurrently the scenario is executed.
+type Options struct {
+	Name        string        // Name of the load test.
+	Iterations  int           // Number of scenario executions, when zero the scenario is executed for Duration.
+	Duration    time.Duration // Duration of the load test, used only when Iterations is zero.
+	Concurrency int           // Number of concurrently executed scenarios, at least one.
+}
+
+// Function Run executes the scenario according to specified options and returns
+// the report with statistics of requests grouped by endpoint. The report is also
+// saved in documentation context, so it is displayed by the preview server.
+func Run(c rest.Context, dc *d.Context, options Options, scenario Scenario) d.LoadReport {
+	if options.Concurrency < 1 {
+		options.Concurrency = 1
+	}
+	rec := &recorder{endpoints: make(map[string]*endpointSamples)}
+	c = rest.WithObserver(c, rec.observe)
+	c = rest.WithReporter(c, failureReporter{})
+	var iterations, failures int64
+	deadline := time.Now().Add(options.Duration)
+	next := func() bool {
+		if options.Iterations > 0 {
+			return atomic.AddInt64(&iterations, 1) <= int64(options.Iterations)
+		}
+		if time.Now().Before(deadline) {
+			atomic.AddInt64(&iterations, 1)
+			return true
+		}
+		return false
+	}
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < options.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// detached documentation context, endpoints documented by reused scenarios are not published
+			detached := d.CreateDocContext()
+			for next() {
+				if err := runIteration(c, detached, scenario); err != nil {
+					atomic.AddInt64(&failures, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	executed := int(atomic.LoadInt64(&iterations))
+	if options.Iterations > 0 && executed > options.Iterations {
+		executed = options.Iterations
+	}
+	report := d.LoadReport{
+		Name:        options.Name,
+		Concurrency: options.Concurrency,
+		Duration:    elapsed,
+		Iterations:  executed,
+		Failures:    int(atomic.LoadInt64(&failures)),
+		Endpoints:   rec.stats(elapsed)}
+	dc.SaveLoadReport(report)
+	return report
+}
+
+// Function runIteration executes the scenario once and converts reported failures into errors.
+func runIteration(c rest.Context, dc *d.Context, scenario Scenario) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if failure, ok := r.(reportedFailure); ok {
+				err = failure
+				return
+			}
+			err = fmt.Errorf("scenario panicked: %v", r)
+		}
+	}()
+	return scenario(c, dc)
+}
+
+// Type reportedFailure is the failure reported by rest functions during load test.
+type reportedFailure struct {
+	message string
+}
+
+func (f reportedFailure) Error() string {
+	return f.message
+}
+
+// Type failureReporter stops the failed iteration without stopping the whole load test.
+type failureReporter struct{}
+
+func (failureReporter) Helper() {}
+
+func (failureReporter) Errorf(format string, args ...interface{}) {
+	panic(reportedFailure{message: fmt.Sprintf(format, args...)})
+}
+
+func (failureReporter) FailNow() {
+	panic(reportedFailure{message: "failed"})
+}
+
+// Type endpointSamples holds latencies and errors of requests to a single endpoint.
+type endpointSamples struct {
+	method    string          // HTTP method name.
+	path      string          // Request path with placeholders for parameters.
+	latencies []time.Duration // Latencies of all requests.
+	errors    int             // Number of failed requests.
+}
+
+// Type recorder collects samples of requests executed during load test.
+type recorder struct {
+	mutex     sync.Mutex
+	endpoints map[string]*endpointSamples
+}
+
+// Function observe saves the sample of completed request.
+func (r *recorder) observe(o rest.Observation) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	key := o.Method + " " + o.Path
+	samples, ok := r.endpoints[key]
+	if !ok {
+		samples = &endpointSamples{method: o.Method, path: o.Path}
+		r.endpoints[key] = samples
+	}
+	samples.latencies = append(samples.latencies, o.Duration)
+	if o.Err != nil {
+		samples.errors++
+	}
+}
+
+// Function stats returns statistics of requests grouped by endpoint, sorted by path and method.
+func (r *recorder) stats(elapsed time.Duration) []d.LoadStats {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stats := make([]d.LoadStats, 0, len(r.endpoints))
+	for _, samples := range r.endpoints {
+		latencies := append(make([]time.Duration, 0, len(samples.latencies)), samples.latencies...)
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		count := len(latencies)
+		stats = append(stats, d.LoadStats{
+			Method:     samples.method,
+			UrlPath:    samples.path,
+			Requests:   count,
+			Errors:     samples.errors,
+			ErrorRate:  float64(samples.errors) / float64(count),
+			Throughput: float64(count) / elapsed.Seconds(),
+			P50:        percentile(latencies, 50),
+			P90:        percentile(latencies, 90),
+			P99:        percentile(latencies, 99),
+			Max:        latencies[count-1]})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].UrlPath == stats[j].UrlPath {
+			return stats[i].Method < stats[j].Method
+		}
+		return stats[i].UrlPath < stats[j].UrlPath
+	})
+	return stats
+}
+
+// Function percentile returns the percentile of sorted latencies using nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
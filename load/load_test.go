@@ -0,0 +1,100 @@
+package load
+
+import (
+	"encoding/json"
+	"errors"
+	d "github.com/wisbery/oxyde/doc"
+	"github.com/wisbery/oxyde/rest"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testContext struct {
+	url string
+}
+
+func (c *testContext) GetUrl() string                { return c.url }
+func (c *testContext) GetAuthorizationToken() string { return "" }
+func (c *testContext) GetHeaders() map[string]string { return nil }
+func (c *testContext) GetVerbose() bool              { return false }
+
+func TestRunIterations(t *testing.T) {
+	var counter int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && atomic.AddInt64(&counter, 1)%4 == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	dc := d.CreateDocContext()
+	params := struct {
+		UserId string `json:"userId"`
+	}{UserId: "1"}
+	scenario := func(c rest.Context, dc *d.Context) error {
+		dc.NewEndpointDocumentation("", "users", "Get user")
+		dc.CollectAll("Get user")
+		rest.HttpGET(c, dc, "/users/{userId}", params, nil, 200)
+		dc.SaveEndpointDocumentation()
+		rest.HttpPOST(c, dc, "/users", nil, nil, 200)
+		return nil
+	}
+	report := Run(&testContext{url: server.URL}, dc, Options{Name: "users", Iterations: 40, Concurrency: 4}, scenario)
+	if report.Iterations != 40 || report.Failures != 10 || report.Concurrency != 4 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if len(report.Endpoints) != 2 {
+		t.Fatalf("expected statistics of two endpoints, got: %+v", report.Endpoints)
+	}
+	get, post := report.Endpoints[1], report.Endpoints[0]
+	if get.Method != "GET" || get.UrlPath != "/users/{userId}" || get.Requests != 40 || get.Errors != 0 {
+		t.Errorf("unexpected GET statistics: %+v", get)
+	}
+	if post.Method != "POST" || post.Requests != 40 || post.Errors != 10 || post.ErrorRate != 0.25 {
+		t.Errorf("unexpected POST statistics: %+v", post)
+	}
+	if get.P50 <= 0 || get.P50 > get.P90 || get.P90 > get.P99 || get.P99 > get.Max || get.Throughput <= 0 {
+		t.Errorf("unexpected GET latencies: %+v", get)
+	}
+	if len(dc.GetLoadReports()) != 1 || len(dc.GetEndpoints()) != 0 {
+		t.Error("report not saved in documentation context or documentation collected")
+	}
+	if _, err := json.Marshal(report); err != nil {
+		t.Errorf("report not serializable to JSON: %v", err)
+	}
+}
+
+func TestRunDuration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	scenario := func(c rest.Context, dc *d.Context) error {
+		if err := rest.TryHttpGET(c, dc, "/health", nil, nil, 200); err != nil {
+			return err
+		}
+		return errors.New("scenario failure")
+	}
+	report := Run(&testContext{url: server.URL}, d.CreateDocContext(), Options{Duration: 50 * time.Millisecond, Concurrency: 2}, scenario)
+	if report.Iterations == 0 || report.Failures != report.Iterations || report.Duration < 50*time.Millisecond {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if report.Endpoints[0].Requests != report.Iterations || report.Endpoints[0].Errors != 0 {
+		t.Errorf("unexpected statistics: %+v", report.Endpoints[0])
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	latencies := make([]time.Duration, 0)
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	if percentile(latencies, 50) != 50*time.Millisecond || percentile(latencies, 90) != 90*time.Millisecond || percentile(latencies, 99) != 99*time.Millisecond {
+		t.Error("unexpected percentiles")
+	}
+	if percentile(latencies[:1], 99) != time.Millisecond || percentile(nil, 50) != 0 {
+		t.Error("unexpected percentiles of short lists")
+	}
+}
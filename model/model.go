@@ -1,13 +1,15 @@
 package model
 
 import (
+	"fmt"
 	d "github.com/wisbery/oxyde/doc"
 	"sort"
 	"strings"
+	"time"
 )
 
 var (
-	HttpMethodOrder = map[string]int{"POST": 1, "PUT": 2, "GET": 3, "DELETE": 4}
+	HttpMethodOrder = map[string]int{"POST": 1, "PUT": 2, "PATCH": 3, "GET": 4, "HEAD": 5, "OPTIONS": 6, "DELETE": 7, "WS": 8}
 )
 
 type Model struct {
@@ -15,6 +17,7 @@ type Model struct {
 	Endpoints     []Endpoint           // List of all endpoints in model.
 	EndpointsById map[string]*Endpoint // Pointers to endpoints by endpoint identifier.
 	RoleNames     []string             // List of tested role names for endpoints.
+	LoadReports   []LoadReport         // List of load test reports.
 }
 
 func CreateModel(dc *d.Context) *Model {
@@ -23,22 +26,24 @@ func CreateModel(dc *d.Context) *Model {
 		Groups:        make([]Group, 0),
 		Endpoints:     make([]Endpoint, 0),
 		EndpointsById: make(map[string]*Endpoint),
-		RoleNames:     dc.GetRoleNames()}
+		RoleNames:     dc.GetRoleNames(),
+		LoadReports:   prepareLoadReports(dc.GetLoadReports())}
 	// create all preview endpoints
 	for _, docEndpoint := range dc.GetEndpoints() {
 		endpoint := Endpoint{
-			Id:           docEndpoint.Id,
-			MethodUp:     strings.ToUpper(docEndpoint.Method),
-			MethodLo:     strings.ToLower(docEndpoint.Method),
-			UrlRoot:      docEndpoint.UrlRoot,
-			UrlPath:      docEndpoint.UrlPath,
-			Tags:         append(make([]string, 0), docEndpoint.Tags...),
-			Summary:      docEndpoint.Summary,
-			Parameters:   prepareFields(docEndpoint.Parameters),
-			RequestBody:  prepareFields(docEndpoint.RequestBody),
-			ResponseBody: prepareFields(docEndpoint.ResponseBody),
-			Examples:     prepareExamples(docEndpoint.Examples),
-			Access:       model.GetAccess(dc, docEndpoint.Method, docEndpoint.UrlPath)}
+			Id:               docEndpoint.Id,
+			MethodUp:         strings.ToUpper(docEndpoint.Method),
+			MethodLo:         strings.ToLower(docEndpoint.Method),
+			UrlRoot:          docEndpoint.UrlRoot,
+			UrlPath:          docEndpoint.UrlPath,
+			Tags:             append(make([]string, 0), docEndpoint.Tags...),
+			Summary:          docEndpoint.Summary,
+			Parameters:       prepareFields(docEndpoint.Parameters),
+			RequestBody:      prepareFields(docEndpoint.RequestBody),
+			RequestMediaType: docEndpoint.RequestMediaType,
+			ResponseBody:     prepareFields(docEndpoint.ResponseBody),
+			Examples:         prepareExamples(docEndpoint.Examples),
+			Access:           model.GetAccess(dc, docEndpoint.Method, docEndpoint.UrlPath)}
 		model.Endpoints = append(model.Endpoints, endpoint)
 	}
 	// prepare endpoint mapping by identifiers
@@ -124,18 +129,19 @@ func CreateGroup(model *Model, name string, ids []string) Group {
 }
 
 type Endpoint struct {
-	Id           string    // Unique endpoint identifier.
-	MethodUp     string    // HTTP method name in uppercase, like GET, POST, PUT or DELETE.
-	MethodLo     string    // HTTP method name in lowercase, like get, post, put or delete.
-	UrlRoot      string    // Root part of request URL.
-	UrlPath      string    // Request path after root part.
-	Tags         []string  // List of tags for endpoint.
-	Summary      string    // Summary describing endpoint.
-	Parameters   []Field   // List of parameter fields.
-	RequestBody  []Field   // List of request body fields.
-	ResponseBody []Field   // List of response body fields.
-	Examples     []Example // List of examples.
-	Access       []string  // List of access rights for roles.
+	Id               string    // Unique endpoint identifier.
+	MethodUp         string    // HTTP method name in uppercase, like GET, POST, PUT, PATCH, DELETE or WS.
+	MethodLo         string    // HTTP method name in lowercase, like get, post, put, patch, delete or ws.
+	UrlRoot          string    // Root part of request URL.
+	UrlPath          string    // Request path after root part.
+	Tags             []string  // List of tags for endpoint.
+	Summary          string    // Summary describing endpoint.
+	Parameters       []Field   // List of parameter fields.
+	RequestBody      []Field   // List of request body fields.
+	RequestMediaType string    // Media type of request body.
+	ResponseBody     []Field   // List of response body fields.
+	Examples         []Example // List of examples.
+	Access           []string  // List of access rights for roles.
 }
 
 type Field struct {
@@ -147,14 +153,40 @@ type Field struct {
 }
 
 type Example struct {
-	Summary      string // Example summary.
-	Description  string // Example detailed description.
-	Method       string // HTTP method name.
-	MethodLo     string // HTTP method name in lowercase.
-	Uri          string // Request URI.
-	StatusCode   int    // HTTP status code.
-	RequestBody  string // Request body as JSON string.
-	ResponseBody string // Response body as JSON string.
+	Summary         string // Example summary.
+	Description     string // Example detailed description.
+	Method          string // HTTP method name.
+	MethodLo        string // HTTP method name in lowercase.
+	Uri             string // Request URI.
+	StatusCode      int    // HTTP status code.
+	ResponseHeaders string // HTTP response headers, one 'Name: value' per line.
+	Latency         string // Measured latency of the request.
+	Curl            string // Curl command executing the request.
+	RequestBody     string // Request body as JSON string.
+	ResponseBody    string // Response body as JSON string.
+}
+
+type LoadReport struct {
+	Name        string      // Name of the load test.
+	Concurrency int         // Number of concurrently running scenarios.
+	Duration    string      // Total duration of the load test.
+	Iterations  int         // Number of executed scenarios.
+	Failures    int         // Number of failed scenarios.
+	Endpoints   []LoadStats // Statistics of requests grouped by endpoint.
+}
+
+type LoadStats struct {
+	MethodUp   string // HTTP method name in uppercase.
+	MethodLo   string // HTTP method name in lowercase.
+	UrlPath    string // Request path after root part.
+	Requests   int    // Number of executed requests.
+	Errors     int    // Number of failed requests.
+	ErrorRate  string // Percentage of failed requests.
+	Throughput string // Number of requests per second.
+	P50        string // Median latency.
+	P90        string // 90th percentile of latency.
+	P99        string // 99th percentile of latency.
+	Max        string // Maximum latency.
 }
 
 func compareEndpoints(e1, e2 *Endpoint) bool {
@@ -216,14 +248,17 @@ func prepareExamples(docExamples []d.Example) []Example {
 	examples := make([]Example, 0)
 	for _, docExample := range docExamples {
 		example := Example{
-			Summary:      docExample.Summary,
-			Description:  docExample.Description,
-			Method:       docExample.Method,
-			MethodLo:     strings.ToLower(docExample.Method),
-			Uri:          docExample.Uri,
-			StatusCode:   docExample.StatusCode,
-			RequestBody:  docExample.RequestBody,
-			ResponseBody: docExample.ResponseBody}
+			Summary:         docExample.Summary,
+			Description:     docExample.Description,
+			Method:          docExample.Method,
+			MethodLo:        strings.ToLower(docExample.Method),
+			Uri:             docExample.Uri,
+			StatusCode:      docExample.StatusCode,
+			ResponseHeaders: strings.TrimSpace(d.FormatHeaders(docExample.ResponseHeaders)),
+			Latency:         prepareLatency(docExample.Timing),
+			Curl:            docExample.Curl,
+			RequestBody:     docExample.RequestBody,
+			ResponseBody:    docExample.ResponseBody}
 		examples = append(examples, example)
 	}
 	// sort examples by status code in ascending order
@@ -232,3 +267,44 @@ func prepareExamples(docExamples []d.Example) []Example {
 	})
 	return examples
 }
+
+func prepareLoadReports(docReports []d.LoadReport) []LoadReport {
+	reports := make([]LoadReport, 0)
+	for _, docReport := range docReports {
+		report := LoadReport{
+			Name:        docReport.Name,
+			Concurrency: docReport.Concurrency,
+			Duration:    docReport.Duration.Round(time.Millisecond).String(),
+			Iterations:  docReport.Iterations,
+			Failures:    docReport.Failures,
+			Endpoints:   make([]LoadStats, 0)}
+		for _, docStats := range docReport.Endpoints {
+			stats := LoadStats{
+				MethodUp:   strings.ToUpper(docStats.Method),
+				MethodLo:   strings.ToLower(docStats.Method),
+				UrlPath:    docStats.UrlPath,
+				Requests:   docStats.Requests,
+				Errors:     docStats.Errors,
+				ErrorRate:  fmt.Sprintf("%.2f%%", docStats.ErrorRate*100),
+				Throughput: fmt.Sprintf("%.2f", docStats.Throughput),
+				P50:        prepareLatencyString(docStats.P50),
+				P90:        prepareLatencyString(docStats.P90),
+				P99:        prepareLatencyString(docStats.P99),
+				Max:        prepareLatencyString(docStats.Max)}
+			report.Endpoints = append(report.Endpoints, stats)
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+func prepareLatency(timing d.Timing) string {
+	if timing.Total == 0 {
+		return ""
+	}
+	return timing.String()
+}
+
+func prepareLatencyString(latency time.Duration) string {
+	return latency.Round(10 * time.Microsecond).String()
+}
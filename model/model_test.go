@@ -1 +1,14 @@
 package model
+
+import "testing"
+
+func TestCompareEndpointsByMethod(t *testing.T) {
+	methods := []string{"POST", "PUT", "PATCH", "GET", "HEAD", "OPTIONS", "DELETE", "WS"}
+	for i := 0; i < len(methods)-1; i++ {
+		e1 := &Endpoint{MethodUp: methods[i], UrlPath: "/users/{userId}"}
+		e2 := &Endpoint{MethodUp: methods[i+1], UrlPath: "/users"}
+		if !compareEndpoints(e1, e2) || compareEndpoints(e2, e1) {
+			t.Errorf("expected %s to be ordered before %s", methods[i], methods[i+1])
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package rest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/wisbery/oxyde/common"
+	"github.com/wisbery/oxyde/doc"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+const (
+	MediaTypeJson      = "application/json"                  // Media type of JSON request bodies.
+	MediaTypeXml       = "application/xml"                   // Media type of XML request bodies.
+	MediaTypeMultipart = "multipart/form-data"               // Media type of multipart request bodies.
+	MediaTypeForm      = "application/x-www-form-urlencoded" // Media type of form-encoded request bodies.
+)
+
+var fileType = reflect.TypeOf(common.File{})
+
+// Type Body wraps the request payload together with the media type it is encoded with.
+// Payloads passed to request functions without this wrapper are encoded as JSON.
+// Payloads of media types other than multipart and form-encoded are encoded
+// using the codec registered for the media type (see common.RegisterCodec).
+type Body struct {
+	MediaType string      // Media type of the encoded payload.
+	Payload   interface{} // Payload to be encoded.
+}
+
+// Function Encoded wraps the payload to be encoded using the codec registered for specified media type.
+func Encoded(mediaType string, payload interface{}) Body {
+	return Body{MediaType: mediaType, Payload: payload}
+}
+
+// Function Xml wraps the payload to be sent as application/xml request body.
+func Xml(payload interface{}) Body {
+	return Body{MediaType: MediaTypeXml, Payload: payload}
+}
+
+// Function Multipart wraps the payload to be sent as multipart/form-data request body.
+// Payload must be a struct, every field becomes a separate part named after the 'form' tag
+// (or 'json' tag when 'form' tag is absent). Fields of type common.File (or pointers and slices
+// of common.File) are sent as file parts, other fields are sent as form fields.
+// Fields with nil pointers and, when tagged with 'omitempty', fields with zero values are skipped.
+func Multipart(payload interface{}) Body {
+	return Body{MediaType: MediaTypeMultipart, Payload: payload}
+}
+
+// Function Form wraps the payload to be sent as application/x-www-form-urlencoded request body.
+// Payload must be a struct, every field becomes a form field named after the 'form' tag
+// (or 'json' tag when 'form' tag is absent). Slice fields are sent as repeated form fields.
+// Fields with nil pointers and, when tagged with 'omitempty', fields with zero values are skipped.
+func Form(payload interface{}) Body {
+	return Body{MediaType: MediaTypeForm, Payload: payload}
+}
+
+// Function unwrapPayload returns the payload and the media type it should be encoded with.
+func unwrapPayload(payload interface{}) (interface{}, string) {
+	switch body := payload.(type) {
+	case Body:
+		return body.Payload, body.MediaType
+	case *Body:
+		if body != nil {
+			return body.Payload, body.MediaType
+		}
+		return nil, MediaTypeJson
+	}
+	return payload, MediaTypeJson
+}
+
+// Type part describes a single part of multipart request body.
+type part struct {
+	name  string       // Name of the part.
+	value string       // Value of the form field.
+	file  *common.File // File content, nil for form fields.
+}
+
+// Function encodeMultipart encodes the payload as multipart/form-data. Returns encoded body,
+// value of the 'Content-Type' header (including boundary) and readable summary of the parts.
+func encodeMultipart(payload interface{}) ([]byte, string, string, error) {
+	parts, err := multipartParts(payload)
+	if err != nil {
+		return nil, "", "", err
+	}
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	summary := make([]string, 0)
+	for _, p := range parts {
+		if p.file == nil {
+			if err = writer.WriteField(p.name, p.value); err != nil {
+				return nil, "", "", err
+			}
+			summary = append(summary, fmt.Sprintf("%s: %s", p.name, p.value))
+			continue
+		}
+		contentType := p.file.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(p.name), escapeQuotes(p.file.Name)))
+		header.Set("Content-Type", contentType)
+		w, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, "", "", err
+		}
+		if _, err = w.Write(p.file.Content); err != nil {
+			return nil, "", "", err
+		}
+		summary = append(summary, fmt.Sprintf("%s: %s (%s, %d bytes)", p.name, p.file.Name, contentType, len(p.file.Content)))
+	}
+	if err = writer.Close(); err != nil {
+		return nil, "", "", err
+	}
+	return body.Bytes(), writer.FormDataContentType(), strings.Join(summary, "\n"), nil
+}
+
+// Function encodeForm encodes the payload as application/x-www-form-urlencoded.
+// Fields are encoded in the order they are declared in the payload struct.
+func encodeForm(payload interface{}) ([]byte, error) {
+	parts, err := multipartParts(payload)
+	if err != nil {
+		return nil, err
+	}
+	pairs := make([]string, 0)
+	for _, p := range parts {
+		if p.file != nil {
+			return nil, fmt.Errorf("file '%s' can not be sent in form-encoded request body", p.name)
+		}
+		pairs = append(pairs, url.QueryEscape(p.name)+"="+url.QueryEscape(p.value))
+	}
+	return []byte(strings.Join(pairs, "&")), nil
+}
+
+// Function multipartParts returns parts of multipart body built from payload struct fields.
+func multipartParts(payload interface{}) ([]part, error) {
+	if common.NilValue(payload) {
+		return nil, nil
+	}
+	payloadType := common.TypeOfValue(payload)
+	if payloadType.Kind() != reflect.Struct {
+		return nil, errors.New("only struct multipart payloads are allowed")
+	}
+	payloadValue := common.ValueOfValue(payload)
+	parts := make([]part, 0)
+	for i := 0; i < payloadType.NumField(); i++ {
+		field := payloadType.Field(i)
+		name, omitEmpty := formTag(field)
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		value := payloadValue.Field(i)
+		if value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				continue
+			}
+			value = value.Elem()
+		}
+		if omitEmpty && value.IsZero() {
+			continue
+		}
+		switch {
+		case value.Type() == fileType:
+			file := value.Interface().(common.File)
+			parts = append(parts, part{name: name, file: &file})
+		case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8:
+			parts = append(parts, part{name: name, value: string(value.Bytes())})
+		case value.Kind() == reflect.Slice:
+			for j := 0; j < value.Len(); j++ {
+				item := reflect.Indirect(value.Index(j))
+				if item.Type() == fileType {
+					file := item.Interface().(common.File)
+					parts = append(parts, part{name: name, file: &file})
+				} else {
+					parts = append(parts, part{name: name, value: fmt.Sprintf("%v", item.Interface())})
+				}
+			}
+		default:
+			parts = append(parts, part{name: name, value: fmt.Sprintf("%v", value.Interface())})
+		}
+	}
+	return parts, nil
+}
+
+// Function formTag returns the name of form field for specified struct field and the flag
+// indicating if the field with zero value is skipped ('omitempty' option). The name is taken
+// from 'form' tag, then from 'json' tag, then the field name is used. Options are taken
+// from 'form' tag (or 'json' tag when 'form' tag is absent).
+func formTag(field reflect.StructField) (string, bool) {
+	tag, ok := field.Tag.Lookup(common.FormTagName)
+	if !ok {
+		tag = field.Tag.Get(common.JsonTagName)
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = common.TagName(field, common.JsonTagName)
+	}
+	if name == "" {
+		name = field.Name
+	}
+	omitEmpty := false
+	for _, option := range strings.Split(options, ",") {
+		if strings.TrimSpace(option) == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty
+}
+
+// Function formFields returns the documentation of form fields built from payload struct fields,
+// named the same way as the fields sent in multipart and form-encoded request bodies.
+func formFields(payload interface{}) []doc.Field {
+	payloadType := common.TypeOfValue(payload)
+	if payloadType.Kind() != reflect.Struct {
+		return doc.ParseObject(payload)
+	}
+	fields := make([]doc.Field, 0)
+	for i := 0; i < payloadType.NumField(); i++ {
+		field := payloadType.Field(i)
+		name, _ := formTag(field)
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		formField := doc.CreateField(field.Type, field)
+		formField.JsonName = name
+		fields = append(fields, formField)
+	}
+	return fields
+}
+
+// Function escapeQuotes escapes quotes and backslashes in the value of Content-Disposition parameter.
+func escapeQuotes(s string) string {
+	return strings.NewReplacer("\\", "\\\\", `"`, "\\\"").Replace(s)
+}
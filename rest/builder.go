@@ -0,0 +1,199 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"github.com/wisbery/oxyde/doc"
+	"net/http"
+	"time"
+)
+
+// Type Requester creates requests executed in the same request context and documentation context.
+type Requester struct {
+	c  Context      // Request context.
+	dc *doc.Context // Documentation context.
+}
+
+// Type Request is HTTP request built step by step and executed using Do or TryDo, like:
+//
+//	rest.New(c, dc).Delete("/users/{userId}").Params(params).Expect(204).Do()
+type Request struct {
+	c          Context                     // Request context.
+	dc         *doc.Context                // Documentation context.
+	method     string                      // HTTP method name.
+	path       string                      // Request path with placeholders for parameters.
+	params     interface{}                 // Request parameters.
+	payload    interface{}                 // Request payload.
+	result     interface{}                 // Result the response body is decoded into.
+	status     int                         // Expected HTTP status code.
+	text       bool                        // Flag indicating if the result is plain text (not JSON).
+	header     http.Header                 // Additional request headers.
+	token      *string                     // Value of 'Authorization' header replacing the token of request context.
+	timeout    time.Duration               // Time limit for the request, zero when the timeout of request context applies.
+	assertions []func(res *Response) error // Assertions verifying the received response.
+}
+
+// Function New creates requester executing requests in specified request context
+// and collecting documentation data in specified documentation context.
+func New(c Context, dc *doc.Context) *Requester {
+	return &Requester{c: c, dc: dc}
+}
+
+// Function Method creates request with specified HTTP method and path.
+// The request expects status code 200 unless changed using Expect.
+func (r *Requester) Method(method string, path string) *Request {
+	return &Request{
+		c:      r.c,
+		dc:     r.dc,
+		method: method,
+		path:   path,
+		status: http.StatusOK}
+}
+
+// Function Get creates HTTP GET request.
+func (r *Requester) Get(path string) *Request {
+	return r.Method(httpGET, path)
+}
+
+// Function Post creates HTTP POST request.
+func (r *Requester) Post(path string) *Request {
+	return r.Method(httpPOST, path)
+}
+
+// Function Put creates HTTP PUT request.
+func (r *Requester) Put(path string) *Request {
+	return r.Method(httpPUT, path)
+}
+
+// Function Patch creates HTTP PATCH request.
+func (r *Requester) Patch(path string) *Request {
+	return r.Method(httpPATCH, path)
+}
+
+// Function Delete creates HTTP DELETE request.
+func (r *Requester) Delete(path string) *Request {
+	return r.Method(httpDELETE, path)
+}
+
+// Function Head creates HTTP HEAD request.
+func (r *Requester) Head(path string) *Request {
+	return r.Method(httpHEAD, path)
+}
+
+// Function Options creates HTTP OPTIONS request.
+func (r *Requester) Options(path string) *Request {
+	return r.Method(httpOPTIONS, path)
+}
+
+// Function Params sets request parameters injected into the path or appended as query string.
+func (r *Request) Params(params interface{}) *Request {
+	r.params = params
+	return r
+}
+
+// Function Body sets request payload, encoded as JSON unless wrapped in Body (see Multipart, Form, Xml, Encoded).
+func (r *Request) Body(payload interface{}) *Request {
+	r.payload = payload
+	return r
+}
+
+// Function Into sets the result the response body is decoded into.
+func (r *Request) Into(result interface{}) *Request {
+	r.result = result
+	r.text = false
+	return r
+}
+
+// Function IntoText sets the result the response body is saved into as simple text (not JSON string!),
+// the result must be a pointer to struct with single string field tagged with json:"-".
+func (r *Request) IntoText(result interface{}) *Request {
+	r.result = result
+	r.text = true
+	return r
+}
+
+// Function Expect sets expected HTTP status code.
+func (r *Request) Expect(status int) *Request {
+	r.status = status
+	return r
+}
+
+// Function Header adds request header, replacing the header with the same name defined in request context.
+func (r *Request) Header(name string, value string) *Request {
+	if r.header == nil {
+		r.header = make(http.Header)
+	}
+	r.header.Add(name, value)
+	return r
+}
+
+// Function Token sets the value of 'Authorization' header, like 'Bearer xyz',
+// replacing the token of request context and tokens of token providers.
+// The request is sent once, also when the server responds with 401 status code.
+func (r *Request) Token(token string) *Request {
+	r.token = &token
+	return r
+}
+
+// Function Timeout sets the time limit for the request, replacing the timeout of request context.
+func (r *Request) Timeout(timeout time.Duration) *Request {
+	r.timeout = timeout
+	return r
+}
+
+// Function Assert adds assertion verifying the received response.
+// Assertions are run after the status code and the duration of the request were verified,
+// the first returned error fails the request, which is then neither documented nor reported
+// to observers as successful.
+func (r *Request) Assert(assertion func(res *Response) error) *Request {
+	r.assertions = append(r.assertions, assertion)
+	return r
+}
+
+// Function ExpectHeader adds assertion verifying the value of response header.
+func (r *Request) ExpectHeader(name string, value string) *Request {
+	return r.Assert(func(res *Response) error {
+		if actual := res.Header.Get(name); actual != value {
+			return fmt.Errorf("unexpected value of header %s, expected: %s, actual: %s", name, value, actual)
+		}
+		return nil
+	})
+}
+
+// Function Do executes the request and returns the metadata of received response.
+// Reports failure when the request failed, the status code is unexpected or any assertion failed.
+func (r *Request) Do() *Response {
+	reporterOf(r.c).Helper()
+	response, err := r.TryDo()
+	failOnError(r.c, err)
+	return response
+}
+
+// Function TryDo executes the request and returns the metadata of received response.
+// Instead of breaking the execution, the failure is returned as *Error.
+// The metadata is returned also when the request failed after the response was received.
+func (r *Request) TryDo() (*Response, error) {
+	c := r.c
+	if r.timeout > 0 {
+		c = WithTimeout(c, r.timeout)
+	}
+	header := r.header
+	if r.token != nil {
+		c = WithContext(c, context.WithValue(contextOf(c), tokenOverrideKey{}, true))
+		header = r.header.Clone()
+		if header == nil {
+			header = make(http.Header)
+		}
+		header.Set("Authorization", *r.token)
+	}
+	cl := newCall(c, r.dc, r.method, r.path, r.params, r.payload, r.result, r.status)
+	cl.text = r.text
+	cl.header = header
+	cl.assertions = r.assertions
+	err := cl.do()
+	if cl.res == nil {
+		return nil, err
+	}
+	response := newResponse(cl.res, cl.timing)
+	return &response, err
+}
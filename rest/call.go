@@ -0,0 +1,373 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/wisbery/oxyde/common"
+	"github.com/wisbery/oxyde/doc"
+	"io"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// Type call holds the state of a single HTTP request executed by this package.
+type call struct {
+	c            Context                     // Request context.
+	dc           *doc.Context                // Documentation context.
+	method       string                      // HTTP method name.
+	path         string                      // Request path with placeholders for parameters.
+	params       interface{}                 // Request parameters.
+	payload      interface{}                 // Request payload, without media type wrapper.
+	mediaType    string                      // Media type the payload is encoded with.
+	result       interface{}                 // Result the response body is decoded into.
+	status       int                         // Expected HTTP status code.
+	text         bool                        // Flag indicating if the result is plain text (not JSON).
+	timeout      time.Duration               // Time limit for the request, zero when not limited.
+	requestPath  string                      // Request path with injected parameters.
+	uri          string                      // Full request URI.
+	requestBody  []byte                      // Encoded request payload.
+	requestText  string                      // Readable form of the request payload used in examples.
+	res          *http.Response              // Received HTTP response.
+	responseBody []byte                      // Body of the received response.
+	duration     time.Duration               // Time elapsed from sending the request until the response body was read.
+	tracer       *tracer                     // Measures phases of the request.
+	timing       doc.Timing                  // Measured durations of request phases.
+	header       http.Header                 // Additional request headers, replacing headers of request context.
+	sentHeader   http.Header                 // Request headers actually sent, after running interceptors.
+	assertions   []func(res *Response) error // Assertions verifying the received response.
+}
+
+// Function newCall creates the state of HTTP request to be executed.
+func newCall(c Context, dc *doc.Context, method string, path string, params interface{}, payload interface{}, result interface{}, status int) *call {
+	payload, mediaType := unwrapPayload(payload)
+	return &call{
+		c:         c,
+		dc:        dc,
+		method:    method,
+		path:      path,
+		params:    params,
+		payload:   payload,
+		mediaType: mediaType,
+		result:    result,
+		status:    status,
+		uri:       prepareUri(c, path)}
+}
+
+// Function do executes HTTP request and notifies observers registered
+// in request context about the outcome.
+func (cl *call) do() error {
+	start := time.Now()
+	err := cl.perform()
+	if cl.duration == 0 {
+		cl.duration = time.Since(start)
+	}
+	cl.finish(err)
+	return err
+}
+
+// Function finish notifies observers registered in request context about the outcome of the request.
+// Failed request stops collecting documentation data, so the next request is not documented instead.
+func (cl *call) finish(err error) {
+	if err != nil {
+		stopCollecting(cl.dc)
+	}
+	notifyObservers(cl, err)
+}
+
+// Function stopCollecting stops collecting documentation data in documentation context, if any.
+func stopCollecting(dc *doc.Context) {
+	if dc != nil {
+		dc.StopCollecting()
+	}
+}
+
+// Function perform executes HTTP request, verifies the response status code,
+// decodes the response body into result and collects documentation data.
+func (cl *call) perform() error {
+	if err := cl.prepare(); err != nil {
+		return err
+	}
+	ctx, cancel := cl.context()
+	defer cancel()
+	res, err := cl.send(ctx)
+	if err != nil {
+		return cl.error(err)
+	}
+	cl.res = res
+	if cl.text || res.StatusCode != cl.status || !common.NilValue(cl.result) {
+		cl.responseBody, err = readResponseBody(cl.c, res)
+	} else {
+		_, err = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+	}
+	cl.measure()
+	if err != nil {
+		return cl.error(err)
+	}
+	if err = cl.checkStatusCode(); err != nil {
+		return err
+	}
+	if err = cl.checkDuration(); err != nil {
+		return err
+	}
+	if err = cl.checkAssertions(); err != nil {
+		return err
+	}
+	if err = cl.decode(); err != nil {
+		return cl.error(err)
+	}
+	collectDocumentationData(cl)
+	return nil
+}
+
+// Function checkAssertions runs assertions verifying the received response,
+// the first failed assertion fails the request.
+func (cl *call) checkAssertions() error {
+	if len(cl.assertions) == 0 {
+		return nil
+	}
+	response := newResponse(cl.res, cl.timing)
+	for _, assertion := range cl.assertions {
+		if err := assertion(&response); err != nil {
+			return cl.error(err)
+		}
+	}
+	return nil
+}
+
+// Function prepare injects parameters into the request path and prepares the request URI.
+func (cl *call) prepare() error {
+	requestPath, err := prepareRequestPath(cl.path, cl.params)
+	if err != nil {
+		return cl.error(err)
+	}
+	cl.requestPath = requestPath
+	cl.uri = prepareUri(cl.c, requestPath)
+	displayRequestDetails(cl.c, cl.method, cl.uri)
+	return nil
+}
+
+// Function send creates HTTP request bound to specified context, sends it
+// and returns the response, the response body is not read.
+func (cl *call) send(ctx context.Context) (*http.Response, error) {
+	req, err := cl.newRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	setRequestHeaders(cl.c, req)
+	for name, values := range cl.header {
+		req.Header.Del(name)
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	cl.tracer.start = time.Now()
+	return chain(func(req *http.Request) (*http.Response, error) {
+		cl.sentHeader = req.Header.Clone()
+		return clientOf(cl.c).Do(req)
+	}, interceptorsOf(cl.c))(req)
+}
+
+// Function start sends the request bound to the context that stays valid after the response
+// is received, so the response body can be read for as long as needed (streams, WebSockets).
+// The timeout of request context limits only the time of receiving the response headers.
+// Returns the function cancelling the request, to be called when the response is no longer read.
+func (cl *call) start() (context.CancelCauseFunc, error) {
+	ctx, cancel := context.WithCancelCause(contextOf(cl.c))
+	cl.tracer = &tracer{}
+	ctx = withTracer(ctx, cl.tracer)
+	cl.timeout = timeoutOf(cl.c)
+	if cl.timeout > 0 {
+		timer := time.AfterFunc(cl.timeout, func() { cancel(context.DeadlineExceeded) })
+		defer timer.Stop()
+	}
+	res, err := cl.send(ctx)
+	if err != nil {
+		if errors.Is(context.Cause(ctx), context.DeadlineExceeded) {
+			err = fmt.Errorf("%v: %w", err, context.DeadlineExceeded)
+		}
+		cancel(nil)
+		return nil, cl.error(err)
+	}
+	cl.res = res
+	if res.StatusCode != cl.status {
+		cl.responseBody, err = readResponseBody(cl.c, res)
+		cl.measure()
+		cancel(nil)
+		if err != nil {
+			return nil, cl.error(err)
+		}
+		return nil, cl.checkStatusCode()
+	}
+	cl.measure()
+	if err = cl.checkStatusCode(); err != nil {
+		cancel(nil)
+		return nil, err
+	}
+	return cancel, nil
+}
+
+// Function measure saves the duration and the timing of the request
+// measured from sending the request until now.
+func (cl *call) measure() {
+	cl.duration = time.Since(cl.tracer.start)
+	cl.timing = cl.tracer.result(cl.duration)
+	saveResponse(cl.c, cl.res, cl.timing)
+}
+
+// Function context returns the context the request is bound to.
+// The context is limited by the timeout configured for the request context, if any.
+func (cl *call) context() (context.Context, context.CancelFunc) {
+	ctx := contextOf(cl.c)
+	cl.timeout = timeoutOf(cl.c)
+	cl.tracer = &tracer{}
+	ctx = withTracer(ctx, cl.tracer)
+	if cl.timeout > 0 {
+		return context.WithTimeout(ctx, cl.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// Function newRequest creates HTTP request with encoded payload.
+func (cl *call) newRequest(ctx context.Context) (*http.Request, error) {
+	if common.NilValue(cl.payload) {
+		if acceptsPayload(cl.method) {
+			displayRequestPayload(cl.c, "", nil)
+		}
+		return http.NewRequestWithContext(ctx, cl.method, cl.uri, nil)
+	}
+	contentType, err := cl.encode()
+	if err != nil {
+		return nil, err
+	}
+	displayRequestPayload(cl.c, contentType, []byte(cl.requestText))
+	req, err := http.NewRequestWithContext(ctx, cl.method, cl.uri, bytes.NewReader(cl.requestBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", contentType)
+	return req, nil
+}
+
+// Function encode encodes the payload using its media type
+// and returns the value of 'Content-Type' header.
+func (cl *call) encode() (string, error) {
+	switch cl.mediaType {
+	case MediaTypeMultipart:
+		requestBody, contentType, summary, err := encodeMultipart(cl.payload)
+		if err != nil {
+			return "", err
+		}
+		cl.requestBody = requestBody
+		cl.requestText = summary
+		return contentType, nil
+	case MediaTypeForm:
+		requestBody, err := encodeForm(cl.payload)
+		if err != nil {
+			return "", err
+		}
+		cl.requestBody = requestBody
+		cl.requestText = string(requestBody)
+		return MediaTypeForm, nil
+	}
+	codec, ok := common.LookupCodec(cl.mediaType)
+	if !ok {
+		return "", fmt.Errorf("unsupported request media type: %s", cl.mediaType)
+	}
+	requestBody, err := codec.Marshal(cl.payload)
+	if err != nil {
+		return "", err
+	}
+	cl.requestBody = requestBody
+	cl.requestText = codec.Indent(requestBody)
+	return cl.mediaType, nil
+}
+
+// Function checkStatusCode returns an error when actual HTTP response
+// status code differs from the expected one.
+func (cl *call) checkStatusCode() error {
+	// display the returned status code if the same as expected
+	if cl.c.GetVerbose() {
+		fmt.Printf("\n<=== STATUS:\n%d\n", cl.res.StatusCode)
+	}
+	// check if the expected status code is the same as returned by server
+	if cl.res.StatusCode != cl.status {
+		return &Error{
+			Method:         cl.method,
+			Uri:            cl.uri,
+			ExpectedStatus: cl.status,
+			ActualStatus:   cl.res.StatusCode,
+			ResponseBody:   cl.responseBody}
+	}
+	return nil
+}
+
+// Function checkDuration returns an error when the request took longer
+// than the time limit defined for the endpoint.
+func (cl *call) checkDuration() error {
+	if cl.c.GetVerbose() {
+		fmt.Printf("\n<=== DURATION:\n%v\n", cl.duration)
+	}
+	if maxDuration := maxDurationOf(cl.c, cl.method, cl.path); maxDuration > 0 && cl.duration > maxDuration {
+		return &Error{
+			Method:         cl.method,
+			Uri:            cl.uri,
+			ExpectedStatus: cl.status,
+			ActualStatus:   cl.res.StatusCode,
+			ResponseBody:   cl.responseBody,
+			Duration:       cl.duration,
+			MaxDuration:    maxDuration}
+	}
+	return nil
+}
+
+// Function decode decodes the response body into result.
+func (cl *call) decode() error {
+	if common.NilValue(cl.result) {
+		return nil
+	}
+	if cl.text {
+		resultFields := doc.ParseObject(cl.result)
+		if len(resultFields) == 1 && resultFields[0].JsonName == "-" && resultFields[0].JsonType == "string" {
+			reflect.ValueOf(cl.result).Elem().Field(0).SetString(string(cl.responseBody))
+		}
+		return nil
+	}
+	codec := responseCodec(cl.res)
+	if err := codec.Unmarshal(cl.responseBody, cl.result); err != nil {
+		return err
+	}
+	if _, ok := codec.(common.JsonCodec); ok {
+		return checkUnknownFields(cl.c, cl.method, cl.uri, cl.responseBody, cl.result)
+	}
+	return nil
+}
+
+// Function responseCodec returns the codec for the media type of the response body.
+// Response bodies of unknown media type are decoded as JSON.
+func responseCodec(res *http.Response) common.Codec {
+	if codec, ok := common.LookupCodec(res.Header.Get("Content-Type")); ok {
+		return codec
+	}
+	return common.JsonCodec{}
+}
+
+// Function error creates *Error describing the failure of the request.
+func (cl *call) error(err error) error {
+	e := &Error{
+		Method:         cl.method,
+		Uri:            cl.uri,
+		ExpectedStatus: cl.status,
+		Err:            err}
+	if cl.res != nil {
+		e.ActualStatus = cl.res.StatusCode
+		e.ResponseBody = cl.responseBody
+	}
+	if cl.timeout > 0 && errors.Is(err, context.DeadlineExceeded) {
+		e.Timeout = cl.timeout
+	}
+	return e
+}
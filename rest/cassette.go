@@ -0,0 +1,310 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	CassetteRecord        = iota // Every request is sent to the server and recorded.
+	CassetteReplay               // Every request is answered with recorded response, no request is sent to the server.
+	CassetteRecordMissing        // Recorded responses are replayed, requests without recorded response are sent and recorded.
+)
+
+const (
+	MatchMethod = 1 << iota // Recorded request matches when HTTP methods are equal.
+	MatchPath               // Recorded request matches when URL paths are equal.
+	MatchQuery              // Recorded request matches when URL query strings are equal.
+	MatchBody               // Recorded request matches when request bodies are equal.
+	MatchAll    = MatchMethod | MatchPath | MatchQuery | MatchBody
+)
+
+// Headers that are never saved in cassette files, because they carry secrets.
+var secretHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// Type Cassette records requests and responses and replays them later without the server.
+// Replayed requests are processed like real ones, so documentation can be collected
+// from replayed responses as well. WebSocket connections and streaming responses (Server-Sent Events,
+// NDJSON) are neither recorded nor replayed, such requests are sent to the server in every mode,
+// including CassetteReplay. Cookies set by recorded responses keep their names and attributes,
+// their values are replaced with "<redacted>" unless KeepCookies is set, so replayed responses
+// set the same cookies, but with redacted values.
+type Cassette struct {
+	Path         string        // Path of the cassette file.
+	Mode         int           // Recording mode, like CassetteRecord or CassetteReplay.
+	Match        int           // Combination of Match... flags defining which parts of requests must be equal.
+	KeepCookies  bool          // Flag indicating if values of cookies set by responses are recorded unchanged.
+	mutex        sync.Mutex    // Guards interactions shared by concurrent requests.
+	interactions []Interaction // Recorded interactions.
+	replayed     []bool        // Flags indicating which interactions were already replayed.
+}
+
+// Type Interaction holds recorded request and response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`  // Recorded request.
+	Response RecordedResponse `json:"response"` // Recorded response.
+}
+
+// Type RecordedRequest holds the recorded request details.
+type RecordedRequest struct {
+	Method     string      `json:"method"`               // HTTP method name.
+	Url        string      `json:"url"`                  // Request URL.
+	Header     http.Header `json:"header,omitempty"`     // Request headers, without secrets.
+	Body       string      `json:"body,omitempty"`       // Request body, when it is valid UTF-8 text.
+	BodyBase64 []byte      `json:"bodyBase64,omitempty"` // Request body, when it is not valid UTF-8 text.
+}
+
+// Type RecordedResponse holds the recorded response details.
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`           // HTTP status code.
+	Header     http.Header `json:"header,omitempty"`     // Response headers, without secrets.
+	Body       string      `json:"body,omitempty"`       // Response body, when it is valid UTF-8 text.
+	BodyBase64 []byte      `json:"bodyBase64,omitempty"` // Response body, when it is not valid UTF-8 text.
+}
+
+// Function NewCassette creates cassette working in specified mode. Interactions recorded earlier
+// are loaded from the cassette file, if it exists. In CassetteReplay mode the file must exist.
+// Requests are matched by method, path and query, use Match field to change it.
+func NewCassette(path string, mode int) (*Cassette, error) {
+	cassette := &Cassette{
+		Path:         path,
+		Mode:         mode,
+		Match:        MatchMethod | MatchPath | MatchQuery,
+		interactions: make([]Interaction, 0)}
+	if mode == CassetteRecord {
+		return cassette, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && mode == CassetteRecordMissing {
+			return cassette, nil
+		}
+		return nil, err
+	}
+	if err = json.Unmarshal(content, &cassette.interactions); err != nil {
+		return nil, fmt.Errorf("invalid cassette file %s: %w", path, err)
+	}
+	cassette.replayed = make([]bool, len(cassette.interactions))
+	return cassette, nil
+}
+
+// Function WithCassette returns request context recording or replaying requests using specified cassette.
+// Call Save on the cassette after all requests were executed to write recorded interactions to file.
+func WithCassette(c Context, cassette *Cassette) Context {
+	return WithInterceptors(c, cassette.intercept)
+}
+
+// Function Save writes all recorded interactions to the cassette file.
+func (c *Cassette) Save() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	content, err := json.MarshalIndent(c.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(c.Path); dir != "" {
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(c.Path, content, 0644)
+}
+
+// Function isStreamRequest returns true when the request opens WebSocket connection
+// or accepts streaming response (Server-Sent Events, NDJSON).
+func isStreamRequest(req *http.Request) bool {
+	if strings.EqualFold(req.Header.Get("Upgrade"), "websocket") {
+		return true
+	}
+	for _, accept := range strings.Split(req.Header.Get("Accept"), ",") {
+		if isStreaming(strings.TrimSpace(accept)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Function Interactions returns all recorded interactions.
+func (c *Cassette) Interactions() []Interaction {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append(make([]Interaction, 0), c.interactions...)
+}
+
+// Function intercept replays recorded response or sends the request and records the response.
+func (c *Cassette) intercept(req *http.Request, next Handler) (*http.Response, error) {
+	if isStreamRequest(req) {
+		return next(req)
+	}
+	requestBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	if c.Mode != CassetteRecord {
+		if interaction := c.find(req, requestBody); interaction != nil {
+			return interaction.Response.toResponse(req), nil
+		}
+		if c.Mode == CassetteReplay {
+			return nil, fmt.Errorf("no recorded interaction in cassette %s matches request %s %s", c.Path, req.Method, req.URL)
+		}
+	}
+	res, err := next(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusSwitchingProtocols || isStreaming(res.Header.Get("Content-Type")) {
+		// upgraded connections and streams are read as they arrive, they are passed through unrecorded
+		return res, nil
+	}
+	responseBody, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(responseBody))
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			Url:    req.URL.String(),
+			Header: withoutSecrets(req.Header)},
+		Response: RecordedResponse{
+			StatusCode: res.StatusCode,
+			Header:     c.responseHeader(res)}}
+	interaction.Request.Body, interaction.Request.BodyBase64 = splitBody(requestBody)
+	interaction.Response.Body, interaction.Response.BodyBase64 = splitBody(responseBody)
+	c.mutex.Lock()
+	c.interactions = append(c.interactions, interaction)
+	c.replayed = append(c.replayed, true)
+	c.mutex.Unlock()
+	return res, nil
+}
+
+// Function responseHeader returns recorded headers of the response, without secrets
+// except cookies, which are recorded with redacted values unless KeepCookies is set.
+func (c *Cassette) responseHeader(res *http.Response) http.Header {
+	header := withoutSecrets(res.Header)
+	for _, cookie := range res.Cookies() {
+		if !c.KeepCookies {
+			cookie.Value = redacted
+		}
+		header.Add("Set-Cookie", cookie.String())
+	}
+	return header
+}
+
+// Function find returns the first matching interaction that was not replayed yet.
+// When all matching interactions were already replayed, the last one is returned.
+func (c *Cassette) find(req *http.Request, body []byte) *Interaction {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	last := -1
+	for i := range c.interactions {
+		if c.matches(&c.interactions[i].Request, req, body) {
+			if !c.replayed[i] {
+				c.replayed[i] = true
+				return &c.interactions[i]
+			}
+			last = i
+		}
+	}
+	if last >= 0 {
+		return &c.interactions[last]
+	}
+	return nil
+}
+
+// Function matches checks if the recorded request matches the request being executed.
+func (c *Cassette) matches(recorded *RecordedRequest, req *http.Request, body []byte) bool {
+	if c.Match&MatchMethod != 0 && recorded.Method != req.Method {
+		return false
+	}
+	recordedUrl, err := req.URL.Parse(recorded.Url)
+	if err != nil {
+		return false
+	}
+	if c.Match&MatchPath != 0 && recordedUrl.Path != req.URL.Path {
+		return false
+	}
+	if c.Match&MatchQuery != 0 && recordedUrl.Query().Encode() != req.URL.Query().Encode() {
+		return false
+	}
+	if c.Match&MatchBody != 0 && !bytes.Equal(joinBody(recorded.Body, recorded.BodyBase64), body) {
+		return false
+	}
+	return true
+}
+
+// Function toResponse creates HTTP response from recorded response.
+func (r *RecordedResponse) toResponse(req *http.Request) *http.Response {
+	body := joinBody(r.Body, r.BodyBase64)
+	header := r.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req}
+}
+
+// Function readRequestBody reads the request body and replaces it with an unread copy.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+	content, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(content))
+	return content, nil
+}
+
+// Function withoutSecrets returns a copy of HTTP headers without headers carrying secrets.
+func withoutSecrets(header http.Header) http.Header {
+	clone := header.Clone()
+	for _, name := range secretHeaders {
+		clone.Del(name)
+	}
+	return clone
+}
+
+// Function splitBody returns the body as text when it is valid UTF-8, otherwise as binary.
+func splitBody(body []byte) (string, []byte) {
+	if utf8.Valid(body) {
+		return string(body), nil
+	}
+	return "", body
+}
+
+// Function joinBody returns the body saved either as text or as binary.
+func joinBody(text string, binary []byte) []byte {
+	if binary != nil {
+		return binary
+	}
+	return []byte(text)
+}
@@ -0,0 +1,190 @@
+package rest
+
+import (
+	"context"
+	"github.com/wisbery/oxyde/common"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	defaultClient  = &http.Client{} // Client used when request context does not provide its own client.
+	defaultTimeout int64            // Time limit used when request context does not define its own timeout.
+)
+
+// Interface for request contexts providing own failure reporter.
+// When request context does not implement this interface,
+// failures are reported using the default reporter (see common.SetReporter).
+type ReporterContext interface {
+	GetReporter() common.Reporter // Returns reporter notified about failed requests.
+}
+
+// Interface for request contexts providing own HTTP client.
+// Implement this interface to configure timeouts, TLS settings, proxies,
+// connection reuse or instrumentation of executed requests.
+type ClientContext interface {
+	GetHttpClient() *http.Client // Returns HTTP client used to execute requests.
+}
+
+// Interface for request contexts binding requests to context.Context.
+// Requests are cancelled when the returned context is cancelled or its deadline expires.
+type CancelContext interface {
+	GetContext() context.Context // Returns context the requests are bound to.
+}
+
+// Interface for request contexts limiting the duration of each request.
+type TimeoutContext interface {
+	GetTimeout() time.Duration // Returns time limit for a single request, zero when not limited.
+}
+
+// Type extendedContext wraps request context and overrides its optional settings.
+// Instances are created using With... functions and are never modified after creation.
+type extendedContext struct {
+	Context                                       // Wrapped request context.
+	reporter             common.Reporter          // Reporter notified about failed requests.
+	client               *http.Client             // HTTP client used to execute requests.
+	ctx                  context.Context          // Context the requests are bound to.
+	timeout              time.Duration            // Time limit for a single request.
+	interceptors         []Interceptor            // Interceptors run around every request.
+	response             *Response                // Structure the metadata of received response is saved in.
+	observers            []Observer               // Observers notified about completed requests.
+	maxDuration          time.Duration            // Time limit for a single request, exceeding it fails the request.
+	endpointMaxDurations map[string]time.Duration // Time limits for requests to specific endpoints.
+	strictMode           *StrictMode              // Handling of response properties not declared in the result.
+}
+
+// Function extend returns a copy of the extended context when the specified context
+// is already extended, otherwise creates new extended context wrapping the specified one.
+func extend(c Context) *extendedContext {
+	if e, ok := c.(*extendedContext); ok {
+		ec := *e
+		return &ec
+	}
+	return &extendedContext{Context: c}
+}
+
+// Function WithReporter returns request context reporting failed requests using specified reporter.
+// Passing testing.TB reports failed requests to the test being executed.
+func WithReporter(c Context, r common.Reporter) Context {
+	e := extend(c)
+	e.reporter = r
+	return e
+}
+
+func (e *extendedContext) GetReporter() common.Reporter {
+	if e.reporter != nil {
+		return e.reporter
+	}
+	if rc, ok := e.Context.(ReporterContext); ok {
+		return rc.GetReporter()
+	}
+	return nil
+}
+
+// Function reporterOf returns the reporter for specified request context.
+func reporterOf(c Context) common.Reporter {
+	if rc, ok := c.(ReporterContext); ok {
+		if r := rc.GetReporter(); r != nil {
+			return r
+		}
+	}
+	return common.GetReporter()
+}
+
+// Function WithClient returns request context executing requests using specified HTTP client.
+func WithClient(c Context, client *http.Client) Context {
+	e := extend(c)
+	e.client = client
+	return e
+}
+
+// Function WithTransport returns request context executing requests
+// using HTTP client with specified transport.
+func WithTransport(c Context, transport http.RoundTripper) Context {
+	return WithClient(c, &http.Client{Transport: transport})
+}
+
+func (e *extendedContext) GetHttpClient() *http.Client {
+	if e.client != nil {
+		return e.client
+	}
+	if cc, ok := e.Context.(ClientContext); ok {
+		return cc.GetHttpClient()
+	}
+	return nil
+}
+
+// Function clientOf returns HTTP client for specified request context.
+func clientOf(c Context) *http.Client {
+	if cc, ok := c.(ClientContext); ok {
+		if client := cc.GetHttpClient(); client != nil {
+			return client
+		}
+	}
+	return defaultClient
+}
+
+// Function WithContext returns request context executing requests bound to specified context.
+// Requests are cancelled when ctx is cancelled or its deadline expires.
+func WithContext(c Context, ctx context.Context) Context {
+	e := extend(c)
+	e.ctx = ctx
+	return e
+}
+
+// Function WithTimeout returns request context limiting the duration of each request
+// to specified timeout. Request exceeding the timeout fails with *Error having Timeout set.
+func WithTimeout(c Context, timeout time.Duration) Context {
+	e := extend(c)
+	e.timeout = timeout
+	return e
+}
+
+// Function SetDefaultTimeout sets the time limit for requests executed
+// with request contexts that do not define their own timeout.
+// Zero timeout (the default) means that requests are not limited.
+func SetDefaultTimeout(timeout time.Duration) {
+	atomic.StoreInt64(&defaultTimeout, int64(timeout))
+}
+
+func (e *extendedContext) GetContext() context.Context {
+	if e.ctx != nil {
+		return e.ctx
+	}
+	if cc, ok := e.Context.(CancelContext); ok {
+		return cc.GetContext()
+	}
+	return nil
+}
+
+func (e *extendedContext) GetTimeout() time.Duration {
+	if e.timeout > 0 {
+		return e.timeout
+	}
+	if tc, ok := e.Context.(TimeoutContext); ok {
+		return tc.GetTimeout()
+	}
+	return 0
+}
+
+// Function contextOf returns the context the requests
+// executed with specified request context are bound to.
+func contextOf(c Context) context.Context {
+	if cc, ok := c.(CancelContext); ok {
+		if ctx := cc.GetContext(); ctx != nil {
+			return ctx
+		}
+	}
+	return context.Background()
+}
+
+// Function timeoutOf returns the time limit for requests executed with specified request context.
+func timeoutOf(c Context) time.Duration {
+	if tc, ok := c.(TimeoutContext); ok {
+		if timeout := tc.GetTimeout(); timeout > 0 {
+			return timeout
+		}
+	}
+	return time.Duration(atomic.LoadInt64(&defaultTimeout))
+}
@@ -0,0 +1,70 @@
+package rest
+
+import (
+	"github.com/wisbery/oxyde/doc"
+	"net/http"
+	"strings"
+)
+
+// Value replacing secrets in documented request headers, curl commands, HAR files and cassettes.
+const redacted = "<redacted>"
+
+// Function curlCommand returns curl command executing the request, with secrets redacted.
+// Returns empty string for requests that can not be executed using curl, like WebSocket connections.
+func curlCommand(cl *call) string {
+	if cl.method == MethodWS {
+		return ""
+	}
+	body := string(cl.requestBody)
+	var form []string
+	if cl.mediaType == MediaTypeMultipart {
+		body = ""
+		parts, _ := multipartParts(cl.payload)
+		for _, p := range parts {
+			if p.file == nil {
+				form = append(form, p.name+"="+p.value)
+			} else if p.file.ContentType != "" {
+				form = append(form, p.name+"=@"+p.file.Name+";type="+p.file.ContentType)
+			} else {
+				form = append(form, p.name+"=@"+p.file.Name)
+			}
+		}
+	}
+	return doc.CurlCommand(cl.method, cl.uri, redactSecrets(cl.sentHeader), body, form)
+}
+
+// Function redactSecrets returns a copy of HTTP headers with values of headers carrying secrets redacted.
+// Besides the headers never saved in cassette files, headers with names containing words
+// like token, secret, key or password are redacted. The authentication scheme, like Bearer, is preserved.
+func redactSecrets(header http.Header) http.Header {
+	clone := header.Clone()
+	for name, values := range clone {
+		if !isSecretHeader(name) {
+			continue
+		}
+		for i, value := range values {
+			if scheme, _, found := strings.Cut(value, " "); found && strings.HasSuffix(name, "Authorization") {
+				values[i] = scheme + " " + redacted
+			} else {
+				values[i] = redacted
+			}
+		}
+	}
+	return clone
+}
+
+// Function isSecretHeader returns true when the header with specified name carries secrets.
+func isSecretHeader(name string) bool {
+	for _, secret := range secretHeaders {
+		if strings.EqualFold(name, secret) {
+			return true
+		}
+	}
+	lower := strings.ToLower(name)
+	for _, word := range []string{"token", "secret", "key", "password"} {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package rest
+
+import (
+	"encoding/json"
+	"github.com/wisbery/oxyde/common"
+	"github.com/wisbery/oxyde/doc"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var reOperationName = regexp.MustCompile(`^\s*(?:query|mutation|subscription)\s+([_A-Za-z][_0-9A-Za-z]*)`) // Matches the name of GraphQL operation.
+
+// Type GraphQLRequest is the body of GraphQL request.
+type GraphQLRequest struct {
+	Query         string      `json:"query"`                   // GraphQL document with the operation to be executed.
+	OperationName string      `json:"operationName,omitempty"` // Name of the operation to be executed.
+	Variables     interface{} `json:"variables,omitempty"`     // Values of variables used in the operation.
+}
+
+// Type graphQLResponse is the body of GraphQL response.
+type graphQLResponse struct {
+	Data       json.RawMessage        `json:"data"`       // Result of the operation.
+	Errors     GraphQLErrors          `json:"errors"`     // Errors reported by the server.
+	Extensions map[string]interface{} `json:"extensions"` // Additional data returned by the server.
+}
+
+// Type GraphQLError is a single error reported in GraphQL response.
+type GraphQLError struct {
+	Message    string                 `json:"message"`              // Description of the error.
+	Locations  []GraphQLLocation      `json:"locations,omitempty"`  // Locations in the GraphQL document the error relates to.
+	Path       []interface{}          `json:"path,omitempty"`       // Path of the response field the error relates to.
+	Extensions map[string]interface{} `json:"extensions,omitempty"` // Additional error details.
+}
+
+// Type GraphQLLocation is a location in GraphQL document.
+type GraphQLLocation struct {
+	Line   int `json:"line"`   // Line number, starting from 1.
+	Column int `json:"column"` // Column number, starting from 1.
+}
+
+// Type GraphQLErrors is the list of errors reported in GraphQL response.
+type GraphQLErrors []GraphQLError
+
+// Function Error returns the messages of all reported errors.
+func (e GraphQLErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Message)
+	}
+	return "GraphQL errors: " + strings.Join(messages, "; ")
+}
+
+// Function HttpGraphQL executes GraphQL operation and decodes returned data into result.
+// Reports failure when the status code is not 200 or the response contains errors.
+func HttpGraphQL(c Context, dc *doc.Context, path string, query string, operationName string, variables interface{}, result interface{}) {
+	reporterOf(c).Helper()
+	failOnError(c, TryHttpGraphQL(c, dc, path, query, operationName, variables, result))
+}
+
+// Function TryHttpGraphQL executes GraphQL operation and decodes returned data into result.
+// Returns *Error when the status code is not 200 or the response contains errors,
+// in the latter case the cause of the error is GraphQLErrors. Strict decoding (see WithStrictDecoding)
+// applies to the returned data only, not to the members of the response envelope.
+// Each operation is documented as a separate endpoint with the path followed by the operation name,
+// like /graphql#GetUser, variables are documented as parameters and data as the response body.
+func TryHttpGraphQL(c Context, dc *doc.Context, path string, query string, operationName string, variables interface{}, result interface{}) (err error) {
+	defer func() {
+		if err != nil {
+			stopCollecting(dc)
+		}
+	}()
+	payload := GraphQLRequest{
+		Query:         query,
+		OperationName: operationName,
+		Variables:     variables}
+	response := graphQLResponse{}
+	// strict decoding applies only to the data decoded into result, not to the response envelope
+	cl := newCall(WithStrictDecoding(c, StrictOff), nil, httpPOST, path, nil, payload, &response, http.StatusOK)
+	if err = cl.do(); err != nil {
+		return err
+	}
+	if len(response.Errors) > 0 {
+		return cl.error(response.Errors)
+	}
+	if !common.NilValue(result) && len(response.Data) > 0 {
+		if err = json.Unmarshal(response.Data, result); err != nil {
+			return cl.error(err)
+		}
+		if err = checkUnknownFields(c, cl.method, cl.uri, response.Data, result); err != nil {
+			return cl.error(err)
+		}
+	}
+	if operationName == "" {
+		if match := reOperationName.FindStringSubmatch(query); match != nil {
+			operationName = match[1]
+		}
+	}
+	requestText := strings.TrimSpace(query)
+	if !common.NilValue(variables) {
+		if data, err := json.Marshal(variables); err == nil {
+			requestText += "\n\n" + common.PrettyPrint(data)
+		}
+	}
+	documented := *cl
+	documented.dc = dc
+	if operationName != "" {
+		documented.path = path + "#" + operationName
+	}
+	documented.params = variables
+	documented.payload = nil
+	documented.result = result
+	documented.requestText = requestText
+	collectDocumentationData(&documented)
+	return nil
+}
@@ -0,0 +1,321 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const harVersion = "1.2" // Version of HTTP Archive format.
+
+// Type Har records all requests and responses in HTTP Archive (HAR) format,
+// which can be opened in browser developer tools and other HAR viewers.
+// Requests are recorded regardless of the documentation collecting mode.
+// Values of headers carrying secrets are redacted.
+type Har struct {
+	Path    string     // Path of the HAR file.
+	mutex   sync.Mutex // Guards entries shared by concurrent requests.
+	entries []HarEntry // Recorded entries.
+}
+
+// Type HarEntry is a single request and response recorded in HAR file.
+type HarEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`  // Time when the request was started, in ISO 8601 format.
+	Time            float64     `json:"time"`             // Total duration of the request in milliseconds.
+	Request         HarRequest  `json:"request"`          // Request details.
+	Response        HarResponse `json:"response"`         // Response details.
+	Cache           struct{}    `json:"cache"`            // Cache usage, always empty.
+	Timings         HarTimings  `json:"timings"`          // Durations of request phases.
+	Error           string      `json:"_error,omitempty"` // Error that prevented receiving the response.
+}
+
+// Type HarRequest holds the recorded request details.
+type HarRequest struct {
+	Method      string       `json:"method"`             // HTTP method name.
+	Url         string       `json:"url"`                // Request URL.
+	HttpVersion string       `json:"httpVersion"`        // HTTP protocol version.
+	Cookies     []HarCookie  `json:"cookies"`            // Request cookies, with redacted values.
+	Headers     []HarHeader  `json:"headers"`            // Request headers.
+	QueryString []HarHeader  `json:"queryString"`        // Parameters of the query string.
+	PostData    *HarPostData `json:"postData,omitempty"` // Request body, when present.
+	HeadersSize int          `json:"headersSize"`        // Size of request headers, always -1 (unknown).
+	BodySize    int          `json:"bodySize"`           // Size of request body in bytes.
+}
+
+// Type HarResponse holds the recorded response details.
+type HarResponse struct {
+	Status      int         `json:"status"`      // HTTP status code, zero when no response was received.
+	StatusText  string      `json:"statusText"`  // HTTP status text.
+	HttpVersion string      `json:"httpVersion"` // HTTP protocol version.
+	Cookies     []HarCookie `json:"cookies"`     // Response cookies, with redacted values.
+	Headers     []HarHeader `json:"headers"`     // Response headers.
+	Content     HarContent  `json:"content"`     // Response body.
+	RedirectUrl string      `json:"redirectURL"` // Value of 'Location' header.
+	HeadersSize int         `json:"headersSize"` // Size of response headers, always -1 (unknown).
+	BodySize    int         `json:"bodySize"`    // Size of response body in bytes, -1 when no response was received.
+}
+
+// Type HarHeader is a name and value pair, used for headers and query string parameters.
+type HarHeader struct {
+	Name  string `json:"name"`  // Name of the header or parameter.
+	Value string `json:"value"` // Value of the header or parameter.
+}
+
+// Type HarCookie is a cookie sent or received.
+type HarCookie struct {
+	Name  string `json:"name"`  // Name of the cookie.
+	Value string `json:"value"` // Value of the cookie, always redacted.
+}
+
+// Type HarPostData holds the request body.
+type HarPostData struct {
+	MimeType string `json:"mimeType"` // Media type of the request body.
+	Text     string `json:"text"`     // Request body.
+}
+
+// Type HarContent holds the response body.
+type HarContent struct {
+	Size     int    `json:"size"`               // Size of the response body in bytes.
+	MimeType string `json:"mimeType"`           // Media type of the response body.
+	Text     string `json:"text,omitempty"`     // Response body, encoded using Base64 when it is not valid UTF-8 text.
+	Encoding string `json:"encoding,omitempty"` // Encoding of the response body text, 'base64' or empty.
+}
+
+// Type HarTimings holds durations of request phases in milliseconds, -1 when not applicable.
+type HarTimings struct {
+	Blocked float64 `json:"blocked"` // Time spent waiting for a connection.
+	Dns     float64 `json:"dns"`     // DNS lookup time.
+	Connect float64 `json:"connect"` // Time of establishing the connection, including TLS handshake.
+	Send    float64 `json:"send"`    // Time of sending the request.
+	Wait    float64 `json:"wait"`    // Time of waiting for the first byte of the response.
+	Receive float64 `json:"receive"` // Time of reading the response body.
+	Ssl     float64 `json:"ssl"`     // TLS handshake time.
+}
+
+// Type harLog is the content of HAR file.
+type harLog struct {
+	Log struct {
+		Version string     `json:"version"` // Version of HAR format.
+		Creator harCreator `json:"creator"` // Application creating HAR file.
+		Entries []HarEntry `json:"entries"` // Recorded entries.
+	} `json:"log"`
+}
+
+// Type harCreator describes the application creating HAR file.
+type harCreator struct {
+	Name    string `json:"name"`    // Name of the application.
+	Version string `json:"version"` // Version of the application.
+}
+
+// Function NewHar creates HTTP Archive recording requests to be saved in file with specified path.
+func NewHar(path string) *Har {
+	return &Har{
+		Path:    path,
+		entries: make([]HarEntry, 0)}
+}
+
+// Function WithHar returns request context recording all requests in specified HTTP Archive.
+// Call Save on the archive after all requests were executed to write recorded entries to file.
+func WithHar(c Context, har *Har) Context {
+	return WithInterceptors(c, har.intercept)
+}
+
+// Function Save writes all recorded entries to the HAR file.
+func (h *Har) Save() error {
+	log := harLog{}
+	log.Log.Version = harVersion
+	log.Log.Creator = harCreator{Name: "oxyde"}
+	log.Log.Entries = h.Entries()
+	content, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(h.Path); dir != "" {
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(h.Path, content, 0644)
+}
+
+// Function Entries returns all recorded entries.
+func (h *Har) Entries() []HarEntry {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return append(make([]HarEntry, 0), h.entries...)
+}
+
+// Function add appends the entry to recorded entries.
+func (h *Har) add(entry HarEntry) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.entries = append(h.entries, entry)
+}
+
+// Function intercept sends the request and records it, when the response body is read and closed.
+func (h *Har) intercept(req *http.Request, next Handler) (*http.Response, error) {
+	requestBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	entry := HarEntry{
+		StartedDateTime: start.Format("2006-01-02T15:04:05.000Z07:00"),
+		Request:         harRequest(req, requestBody)}
+	t := tracerFrom(req.Context())
+	res, err := next(req)
+	if err != nil {
+		entry.Error = err.Error()
+		entry.Response = HarResponse{Cookies: []HarCookie{}, Headers: []HarHeader{}, HeadersSize: -1, BodySize: -1}
+		entry.finish(start, t, nil)
+		h.add(entry)
+		return nil, err
+	}
+	entry.Response = harResponse(res)
+	if res.StatusCode == http.StatusSwitchingProtocols {
+		// upgraded connection is recorded without content, its body must stay readable and writable
+		entry.finish(start, t, []byte{})
+		h.add(entry)
+		return res, nil
+	}
+	res.Body = &harBody{ReadCloser: res.Body, finish: func(body []byte) {
+		entry.finish(start, t, body)
+		h.add(entry)
+	}}
+	return res, nil
+}
+
+// Function finish saves the response body and the durations of request phases in the entry.
+func (entry *HarEntry) finish(start time.Time, t *tracer, body []byte) {
+	total := time.Since(start)
+	entry.Time = milliseconds(total)
+	entry.Timings = HarTimings{Blocked: -1, Dns: -1, Connect: -1, Send: 0, Wait: milliseconds(total), Receive: 0, Ssl: -1}
+	if t != nil {
+		timing := t.result(total)
+		if timing.DNS > 0 {
+			entry.Timings.Dns = milliseconds(timing.DNS)
+		}
+		if timing.Connect > 0 {
+			entry.Timings.Connect = milliseconds(timing.Connect + timing.TLS)
+		}
+		if timing.TLS > 0 {
+			entry.Timings.Ssl = milliseconds(timing.TLS)
+		}
+		if timing.TTFB > 0 {
+			wait := timing.TTFB - timing.DNS - timing.Connect - timing.TLS
+			if wait < 0 {
+				wait = 0
+			}
+			entry.Timings.Wait = milliseconds(wait)
+			entry.Timings.Receive = milliseconds(total - timing.TTFB)
+		}
+	}
+	if body == nil {
+		return
+	}
+	entry.Response.BodySize = len(body)
+	entry.Response.Content.Size = len(body)
+	if utf8.Valid(body) {
+		entry.Response.Content.Text = string(body)
+	} else {
+		entry.Response.Content.Text = base64.StdEncoding.EncodeToString(body)
+		entry.Response.Content.Encoding = "base64"
+	}
+}
+
+// Function harRequest returns recorded details of the request.
+func harRequest(req *http.Request, body []byte) HarRequest {
+	request := HarRequest{
+		Method:      req.Method,
+		Url:         req.URL.String(),
+		HttpVersion: req.Proto,
+		Cookies:     harCookies(req.Cookies()),
+		Headers:     harHeaders(redactSecrets(req.Header)),
+		QueryString: make([]HarHeader, 0),
+		HeadersSize: -1,
+		BodySize:    len(body)}
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			request.QueryString = append(request.QueryString, HarHeader{Name: name, Value: value})
+		}
+	}
+	if len(body) > 0 {
+		request.PostData = &HarPostData{MimeType: req.Header.Get("Content-Type"), Text: string(body)}
+	}
+	return request
+}
+
+// Function harResponse returns recorded details of the response, without the body.
+func harResponse(res *http.Response) HarResponse {
+	mimeType := res.Header.Get("Content-Type")
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	} else if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+		mimeType = mediaType
+	}
+	return HarResponse{
+		Status:      res.StatusCode,
+		StatusText:  http.StatusText(res.StatusCode),
+		HttpVersion: res.Proto,
+		Cookies:     harCookies(res.Cookies()),
+		Headers:     harHeaders(redactSecrets(res.Header)),
+		Content:     HarContent{MimeType: mimeType},
+		RedirectUrl: res.Header.Get("Location"),
+		HeadersSize: -1}
+}
+
+// Function harHeaders returns headers as name and value pairs.
+func harHeaders(header http.Header) []HarHeader {
+	headers := make([]HarHeader, 0, len(header))
+	for name, values := range header {
+		for _, value := range values {
+			headers = append(headers, HarHeader{Name: name, Value: value})
+		}
+	}
+	return headers
+}
+
+// Function harCookies returns cookies with redacted values.
+func harCookies(cookies []*http.Cookie) []HarCookie {
+	result := make([]HarCookie, 0, len(cookies))
+	for _, cookie := range cookies {
+		result = append(result, HarCookie{Name: cookie.Name, Value: redacted})
+	}
+	return result
+}
+
+// Function milliseconds returns the duration in milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Type harBody collects the response body while it is read
+// and records the entry when the body is fully read or closed.
+type harBody struct {
+	io.ReadCloser                   // Original response body.
+	buffer        bytes.Buffer      // Content read so far.
+	once          sync.Once         // Guards recording the entry only once.
+	finish        func(body []byte) // Records the entry with the response body.
+}
+
+func (b *harBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buffer.Write(p[:n])
+	if err == io.EOF {
+		b.once.Do(func() { b.finish(b.buffer.Bytes()) })
+	}
+	return n, err
+}
+
+func (b *harBody) Close() error {
+	b.once.Do(func() { b.finish(b.buffer.Bytes()) })
+	return b.ReadCloser.Close()
+}
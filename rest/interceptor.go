@@ -0,0 +1,56 @@
+package rest
+
+import (
+	"net/http"
+)
+
+// Type Handler sends HTTP request and returns the received response.
+type Handler func(req *http.Request) (*http.Response, error)
+
+// Type Interceptor runs around sending every HTTP request. Interceptor may inspect
+// and modify the request before passing it to the next handler, and inspect
+// or modify the response (including the body) returned by the next handler.
+// Interceptor may also return a response without calling the next handler at all.
+type Interceptor func(req *http.Request, next Handler) (*http.Response, error)
+
+// Interface for request contexts providing interceptors run around every request.
+type InterceptorContext interface {
+	GetInterceptors() []Interceptor // Returns interceptors in order they are run, the first one is the outermost.
+}
+
+// Function WithInterceptors returns request context running specified interceptors
+// around every request. Interceptors are run after the interceptors already
+// registered in request context, the first specified interceptor is the outermost.
+func WithInterceptors(c Context, interceptors ...Interceptor) Context {
+	e := extend(c)
+	e.interceptors = append(append(make([]Interceptor, 0), e.interceptors...), interceptors...)
+	return e
+}
+
+func (e *extendedContext) GetInterceptors() []Interceptor {
+	interceptors := make([]Interceptor, 0)
+	if ic, ok := e.Context.(InterceptorContext); ok {
+		interceptors = append(interceptors, ic.GetInterceptors()...)
+	}
+	return append(interceptors, e.interceptors...)
+}
+
+// Function interceptorsOf returns interceptors registered in specified request context.
+func interceptorsOf(c Context) []Interceptor {
+	if ic, ok := c.(InterceptorContext); ok {
+		return ic.GetInterceptors()
+	}
+	return nil
+}
+
+// Function chain returns handler running interceptors around the specified handler.
+func chain(handler Handler, interceptors []Interceptor) Handler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor := interceptors[i]
+		next := handler
+		handler = func(req *http.Request) (*http.Response, error) {
+			return interceptor(req, next)
+		}
+	}
+	return handler
+}
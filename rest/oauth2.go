@@ -0,0 +1,180 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Time before the token expiration, when the token is considered expired and is refreshed.
+// Tokens valid for a shorter time are refreshed after half of their lifetime.
+const tokenExpiryMargin = 10 * time.Second
+
+// Interface for providers of access tokens passed in 'Authorization' header.
+type TokenProvider interface {
+	Token(ctx context.Context) (string, error) // Returns the value of 'Authorization' header, like 'Bearer xyz'.
+	Invalidate()                               // Discards the cached token, so the next call to Token obtains a new one.
+}
+
+// Function WithTokenProvider returns request context sending the token obtained from
+// specified provider in 'Authorization' header of every request, instead of the token
+// returned by GetAuthorizationToken. When the server responds with 401 status code,
+// the token is invalidated and the request is repeated once with a new token.
+// Requests with the token set explicitly for a single call (see Request.Token) are sent unchanged.
+func WithTokenProvider(c Context, provider TokenProvider) Context {
+	interceptor := func(req *http.Request, next Handler) (*http.Response, error) {
+		if req.Context().Value(tokenOverrideKey{}) != nil {
+			return next(req)
+		}
+		token, err := provider.Token(req.Context())
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", token)
+		res, err := next(req)
+		if err != nil || res.StatusCode != http.StatusUnauthorized || (req.Body != nil && req.GetBody == nil) {
+			return res, err
+		}
+		provider.Invalidate()
+		if token, err = provider.Token(req.Context()); err != nil {
+			return nil, err
+		}
+		retry := req.Clone(req.Context())
+		if req.GetBody != nil {
+			if retry.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		retry.Header.Set("Authorization", token)
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+		return next(retry)
+	}
+	return WithInterceptors(c, interceptor)
+}
+
+// Type tokenOverrideKey is the key of context value marking requests with the token set explicitly.
+type tokenOverrideKey struct{}
+
+// Type OAuth2Provider obtains access tokens from OAuth2/OIDC token endpoint
+// and caches them until they expire.
+type OAuth2Provider struct {
+	TokenUrl     string       // URL of the token endpoint.
+	ClientId     string       // Client identifier.
+	ClientSecret string       // Client secret.
+	Username     string       // Resource owner name, used only in password grant.
+	Password     string       // Resource owner password, used only in password grant.
+	Scopes       []string     // Requested scopes.
+	Client       *http.Client // HTTP client used to call the token endpoint, default client when nil.
+	mutex        sync.Mutex   // Guards the cached token.
+	token        string       // Cached value of 'Authorization' header.
+	expiresAt    time.Time    // Expiration time of the cached token, zero when the token does not expire.
+}
+
+// Function ClientCredentials creates token provider using OAuth2 client credentials grant.
+func ClientCredentials(tokenUrl string, clientId string, clientSecret string, scopes ...string) *OAuth2Provider {
+	return &OAuth2Provider{
+		TokenUrl:     tokenUrl,
+		ClientId:     clientId,
+		ClientSecret: clientSecret,
+		Scopes:       scopes}
+}
+
+// Function PasswordGrant creates token provider using OAuth2 resource owner password credentials grant.
+func PasswordGrant(tokenUrl string, clientId string, clientSecret string, username string, password string, scopes ...string) *OAuth2Provider {
+	return &OAuth2Provider{
+		TokenUrl:     tokenUrl,
+		ClientId:     clientId,
+		ClientSecret: clientSecret,
+		Username:     username,
+		Password:     password,
+		Scopes:       scopes}
+}
+
+// Function Token returns cached token or obtains a new one when there is no valid token cached.
+func (p *OAuth2Provider) Token(ctx context.Context) (string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.token != "" && (p.expiresAt.IsZero() || time.Now().Before(p.expiresAt)) {
+		return p.token, nil
+	}
+	token, expiresIn, err := p.requestToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	p.token = token
+	p.expiresAt = time.Time{}
+	if expiresIn > 0 {
+		p.expiresAt = time.Now().Add(expiresIn - min(tokenExpiryMargin, expiresIn/2))
+	}
+	return p.token, nil
+}
+
+// Function Invalidate discards the cached token.
+func (p *OAuth2Provider) Invalidate() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.token = ""
+	p.expiresAt = time.Time{}
+}
+
+// Function requestToken calls the token endpoint and returns the value
+// of 'Authorization' header and the lifetime of the token.
+func (p *OAuth2Provider) requestToken(ctx context.Context) (string, time.Duration, error) {
+	form := url.Values{}
+	if p.Username != "" {
+		form.Set("grant_type", "password")
+		form.Set("username", p.Username)
+		form.Set("password", p.Password)
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+	if len(p.Scopes) > 0 {
+		form.Set("scope", strings.Join(p.Scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(ctx, httpPOST, p.TokenUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", MediaTypeForm)
+	req.Header.Set("Accept", MediaTypeJson)
+	req.SetBasicAuth(url.QueryEscape(p.ClientId), url.QueryEscape(p.ClientSecret))
+	client := p.Client
+	if client == nil {
+		client = defaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	body, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return "", 0, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("token request failed with status code %d: %s", res.StatusCode, body)
+	}
+	tokenResponse := struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}{}
+	if err = json.Unmarshal(body, &tokenResponse); err != nil {
+		return "", 0, err
+	}
+	if tokenResponse.AccessToken == "" {
+		return "", 0, fmt.Errorf("token response has no access token: %s", body)
+	}
+	tokenType := tokenResponse.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	return tokenType + " " + tokenResponse.AccessToken, time.Duration(tokenResponse.ExpiresIn) * time.Second, nil
+}
@@ -0,0 +1,46 @@
+package rest
+
+import (
+	"time"
+)
+
+// Type Observation describes the outcome of a completed request.
+type Observation struct {
+	Method     string        // HTTP method name.
+	Path       string        // Request path with placeholders for parameters, like /users/{userId}.
+	Uri        string        // Full request URI.
+	StatusCode int           // HTTP status code, zero when no response was received.
+	Duration   time.Duration // Time elapsed from sending the request until the response body was read.
+	Err        error         // Failure of the request, nil when the request succeeded.
+}
+
+// Type Observer is notified about every completed request, both succeeded and failed.
+// Observers may be called concurrently when requests are executed from many goroutines.
+type Observer func(o Observation)
+
+// Function WithObserver returns request context notifying specified observer about every completed request.
+func WithObserver(c Context, observer Observer) Context {
+	e := extend(c)
+	e.observers = append(append(make([]Observer, 0), e.observers...), observer)
+	return e
+}
+
+// Function notifyObservers notifies observers registered in request context about completed request.
+func notifyObservers(cl *call, err error) {
+	e, ok := cl.c.(*extendedContext)
+	if !ok || len(e.observers) == 0 {
+		return
+	}
+	o := Observation{
+		Method:   cl.method,
+		Path:     cl.path,
+		Uri:      cl.uri,
+		Duration: cl.duration,
+		Err:      err}
+	if cl.res != nil {
+		o.StatusCode = cl.res.StatusCode
+	}
+	for _, observer := range e.observers {
+		observer(o)
+	}
+}
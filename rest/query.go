@@ -0,0 +1,205 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/wisbery/oxyde/common"
+	"github.com/wisbery/oxyde/doc"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	rePlaceholder = regexp.MustCompile(`\{[^/{}]+\}`) // Matches placeholders in request path, like {userId}.
+)
+
+// Type queryOptions holds the options of request parameter defined in its tag.
+type queryOptions struct {
+	omitEmpty bool // Parameter with zero value is skipped.
+	comma     bool // Slice values are joined with commas instead of repeating the parameter.
+}
+
+// Function prepareRequestPath injects parameters into placeholders of the request path
+// and appends remaining parameters as query string. Parameters are the fields of params struct,
+// named after the 'query' tag (or 'json' tag when 'query' tag is absent). Supported tag options:
+//
+//	omitempty - parameter with zero value is skipped,
+//	comma     - slice values are joined with commas (ids=1,2) instead of repeating the parameter (ids=1&ids=2).
+//
+// Fields of embedded structs are treated as parameters of the enclosing struct, fields of nested
+// structs are prefixed with the name of the nested struct (address.city). Values of time.Time
+// are formatted using RFC 3339. Returns an error when any placeholder is left unresolved.
+func prepareRequestPath(path string, params interface{}) (string, error) {
+	query := make([]string, 0)
+	if !common.NilValue(params) {
+		paramsType := common.TypeOfValue(params)
+		if paramsType.Kind() != reflect.Struct {
+			return "", errors.New("only struct parameters are allowed")
+		}
+		path, query = appendParameters(path, query, "", common.ValueOfValue(params))
+	}
+	if placeholder := rePlaceholder.FindString(path); placeholder != "" {
+		return "", fmt.Errorf("unresolved placeholder %s in path %s", placeholder, path)
+	}
+	if len(query) > 0 {
+		separator := "?"
+		if strings.Contains(path, "?") {
+			separator = "&"
+		}
+		path = path + separator + strings.Join(query, "&")
+	}
+	return path, nil
+}
+
+// Function appendParameters injects the fields of the struct into path placeholders
+// or appends them to query, returns updated path and query.
+func appendParameters(path string, query []string, prefix string, value reflect.Value) (string, []string) {
+	typ := value.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, options := queryTag(field)
+		if name == "-" {
+			continue
+		}
+		fieldValue := value.Field(i)
+		if fieldValue.Kind() == reflect.Ptr {
+			if fieldValue.IsNil() {
+				continue
+			}
+			fieldValue = fieldValue.Elem()
+		}
+		if field.Anonymous && name == "" && fieldValue.Kind() == reflect.Struct && fieldValue.Type() != timeType {
+			path, query = appendParameters(path, query, prefix, fieldValue)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		name = prefix + name
+		if options.omitEmpty && fieldValue.IsZero() {
+			continue
+		}
+		if fieldValue.Kind() == reflect.Struct && fieldValue.Type() != timeType {
+			path, query = appendParameters(path, query, name+".", fieldValue)
+			continue
+		}
+		values := parameterValues(fieldValue)
+		placeholder := "{" + name + "}"
+		if strings.Contains(path, placeholder) {
+			path = strings.ReplaceAll(path, placeholder, url.PathEscape(strings.Join(values, ",")))
+		} else if options.comma || !isList(fieldValue) {
+			query = append(query, url.QueryEscape(name)+"="+url.QueryEscape(strings.Join(values, ",")))
+		} else {
+			for _, v := range values {
+				query = append(query, url.QueryEscape(name)+"="+url.QueryEscape(v))
+			}
+		}
+	}
+	return path, query
+}
+
+// Function parameterFields returns the documentation of request parameters, named
+// the same way as the parameters injected into the path or appended as query string.
+func parameterFields(params interface{}) []doc.Field {
+	typ := common.TypeOfValue(params)
+	if typ.Kind() != reflect.Struct {
+		return doc.ParseObject(params)
+	}
+	return appendParameterFields(make([]doc.Field, 0), "", typ)
+}
+
+// Function appendParameterFields appends the documentation of the fields of the struct type,
+// like appendParameters does with their values, returns updated fields.
+func appendParameterFields(fields []doc.Field, prefix string, typ reflect.Type) []doc.Field {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, _ := queryTag(field)
+		if name == "-" {
+			continue
+		}
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct && fieldType != timeType {
+			fields = appendParameterFields(fields, prefix, fieldType)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		name = prefix + name
+		if fieldType.Kind() == reflect.Struct && fieldType != timeType {
+			fields = appendParameterFields(fields, name+".", fieldType)
+			continue
+		}
+		parameter := doc.CreateField(fieldType, field)
+		parameter.JsonName = name
+		fields = append(fields, parameter)
+	}
+	return fields
+}
+
+// Function queryTag returns the name and options of the request parameter.
+func queryTag(field reflect.StructField) (string, queryOptions) {
+	tag, ok := field.Tag.Lookup(common.QueryTagName)
+	if !ok {
+		tag = field.Tag.Get(common.JsonTagName)
+	}
+	name, rest, _ := strings.Cut(tag, ",")
+	options := queryOptions{}
+	for _, option := range strings.Split(rest, ",") {
+		switch strings.TrimSpace(option) {
+		case "omitempty":
+			options.omitEmpty = true
+		case "comma":
+			options.comma = true
+		}
+	}
+	return name, options
+}
+
+// Function isList returns true when the value is a slice or an array (except byte slices).
+func isList(value reflect.Value) bool {
+	return (value.Kind() == reflect.Slice || value.Kind() == reflect.Array) && value.Type().Elem().Kind() != reflect.Uint8
+}
+
+// Function parameterValues returns formatted values of the parameter.
+func parameterValues(value reflect.Value) []string {
+	if isList(value) {
+		values := make([]string, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			item := value.Index(i)
+			if item.Kind() == reflect.Ptr {
+				if item.IsNil() {
+					continue
+				}
+				item = item.Elem()
+			}
+			values = append(values, formatParameter(item))
+		}
+		return values
+	}
+	return []string{formatParameter(value)}
+}
+
+// Function formatParameter returns formatted value of a single parameter.
+func formatParameter(value reflect.Value) string {
+	if value.Type() == timeType {
+		return value.Interface().(time.Time).Format(time.RFC3339)
+	}
+	if value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8 {
+		return string(value.Bytes())
+	}
+	return fmt.Sprintf("%v", value.Interface())
+}
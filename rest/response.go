@@ -0,0 +1,53 @@
+package rest
+
+import (
+	"github.com/wisbery/oxyde/doc"
+	"net/http"
+	"time"
+)
+
+// Type Response holds the metadata of received HTTP response.
+type Response struct {
+	StatusCode int            // HTTP status code.
+	Header     http.Header    // Response headers.
+	Cookies    []*http.Cookie // Cookies set by the response.
+	Duration   time.Duration  // Time elapsed from sending the request until the response body was read.
+	Timing     doc.Timing     // Durations of request phases.
+}
+
+// Function WithResponse returns request context saving the metadata of received
+// response in specified structure. The metadata is saved also when the request
+// fails after the response was received, e.g. when the status code is unexpected.
+func WithResponse(c Context, response *Response) Context {
+	e := extend(c)
+	e.response = response
+	return e
+}
+
+// Function Cookie returns the cookie with specified name or nil when no such cookie was set.
+func (r *Response) Cookie(name string) *http.Cookie {
+	for _, cookie := range r.Cookies {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// Function saveResponse saves the metadata of received response
+// in the structure registered in request context, if any.
+func saveResponse(c Context, res *http.Response, timing doc.Timing) {
+	if e, ok := c.(*extendedContext); ok && e.response != nil {
+		*e.response = newResponse(res, timing)
+	}
+}
+
+// Function newResponse returns the metadata of received response.
+func newResponse(res *http.Response, timing doc.Timing) Response {
+	return Response{
+		StatusCode: res.StatusCode,
+		Header:     res.Header.Clone(),
+		Cookies:    res.Cookies(),
+		Duration:   timing.Total,
+		Timing:     timing}
+}
@@ -1,24 +1,23 @@
 package rest
 
 import (
-	"bytes"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/wisbery/oxyde/common"
 	"github.com/wisbery/oxyde/doc"
 	"io/ioutil"
 	"net/http"
-	"net/url"
-	"reflect"
-	"strings"
+	"time"
 )
 
 const (
-	httpGET    = "GET"
-	httpPOST   = "POST"
-	httpPUT    = "PUT"
-	httpDELETE = "DELETE"
+	httpGET     = "GET"
+	httpPOST    = "POST"
+	httpPUT     = "PUT"
+	httpDELETE  = "DELETE"
+	httpPATCH   = "PATCH"
+	httpHEAD    = "HEAD"
+	httpOPTIONS = "OPTIONS"
 )
 
 // Interface for request context. Instances of this interface
@@ -30,121 +29,181 @@ type Context interface {
 	GetVerbose() bool              // Returns flag indicating if executing process should be more verbose.
 }
 
+// Type Error describes the failure of HTTP request executed by functions in this package.
+type Error struct {
+	Method         string        // HTTP method name.
+	Uri            string        // Request URI.
+	ExpectedStatus int           // Expected HTTP status code.
+	ActualStatus   int           // Actual HTTP status code, zero when no response was received.
+	ResponseBody   []byte        // Body of the received response, nil when no response was received.
+	Err            error         // Cause of the failure, nil when only the status code was unexpected.
+	Timeout        time.Duration // Time after which the request timed out, zero when it did not time out.
+	Duration       time.Duration // Duration of the request, set only when it exceeded MaxDuration.
+	MaxDuration    time.Duration // Time limit exceeded by the request, zero when the limit was not exceeded.
+}
+
+// Function Error returns the text describing the failure.
+func (e *Error) Error() string {
+	if e.Timeout > 0 {
+		return fmt.Sprintf("%s %s: request timed out after %v", e.Method, e.Uri, e.Timeout)
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("%s %s: %v", e.Method, e.Uri, e.Err)
+	}
+	if e.MaxDuration > 0 {
+		return fmt.Sprintf("%s %s: request took %v, expected at most %v", e.Method, e.Uri, e.Duration, e.MaxDuration)
+	}
+	return fmt.Sprintf("%s %s: unexpected status code, expected: %d, actual: %d", e.Method, e.Uri, e.ExpectedStatus, e.ActualStatus)
+}
+
+// Function Unwrap returns the cause of the failure.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Function UnexpectedStatus returns true when the request failed
+// only because the returned status code differs from the expected one.
+func (e *Error) UnexpectedStatus() bool {
+	return e.Err == nil && e.ExpectedStatus != e.ActualStatus
+}
+
 // Function HttpGETString executes HTTP GET request and returns simple text result (not JSON string!)
 func HttpGETString(c Context, dc *doc.Context, path string, params interface{}, result interface{}, status int) {
-	requestPath, err := prepareRequestPath(path, params)
-	uri := prepareUri(c, requestPath)
-	displayRequestDetails(c, httpGET, uri)
-	req, err := http.NewRequest(httpGET, uri, nil)
-	common.PanicOnError(err)
-	setRequestHeaders(c, req)
-	client := http.Client{}
-	res, err := client.Do(req)
-	common.PanicOnError(err)
-	panicOnUnexpectedStatusCode(c, status, res)
-	responseBody := readResponseBody(c, res)
-	collectDocumentationData(c, dc, res, httpGET, path, requestPath, params, nil, result, nil, responseBody)
-	resultFields := doc.ParseObject(result)
-	if len(resultFields) == 1 && resultFields[0].JsonName == "-" && resultFields[0].JsonType == "string" {
-		reflect.ValueOf(result).Elem().Field(0).SetString(string(responseBody))
-	}
+	reporterOf(c).Helper()
+	failOnError(c, TryHttpGETString(c, dc, path, params, result, status))
 }
 
 // Function HttpGET executes HTTP GET request and returns JSON result.
 func HttpGET(c Context, dc *doc.Context, path string, params interface{}, result interface{}, status int) {
-	var responseBody []byte
-	requestPath, err := prepareRequestPath(path, params)
-	uri := prepareUri(c, requestPath)
-	displayRequestDetails(c, httpGET, uri)
-	req, err := http.NewRequest(httpGET, uri, nil)
-	common.PanicOnError(err)
-	setRequestHeaders(c, req)
-	client := http.Client{}
-	res, err := client.Do(req)
-	common.PanicOnError(err)
-	panicOnUnexpectedStatusCode(c, status, res)
-	if common.NilValue(result) {
-		responseBody = nil
-	} else {
-		responseBody = readResponseBody(c, res)
-		err = json.Unmarshal(responseBody, result)
-		common.PanicOnError(err)
-	}
-	collectDocumentationData(c, dc, res, httpGET, path, requestPath, params, nil, result, nil, responseBody)
+	reporterOf(c).Helper()
+	failOnError(c, TryHttpGET(c, dc, path, params, result, status))
 }
 
 // Function HttpPOST executes HTTP POST request.
 func HttpPOST(c Context, dc *doc.Context, path string, payload interface{}, result interface{}, status int) {
-	httpCall(c, dc, httpPOST, path, nil, payload, result, status)
+	reporterOf(c).Helper()
+	failOnError(c, TryHttpPOST(c, dc, path, payload, result, status))
 }
 
 // Function HttpPUT executes HTTP PUT request.
 func HttpPUT(c Context, dc *doc.Context, path string, payload interface{}, result interface{}, status int) {
-	httpCall(c, dc, httpPUT, path, nil, payload, result, status)
+	reporterOf(c).Helper()
+	failOnError(c, TryHttpPUT(c, dc, path, payload, result, status))
 }
 
 // Function HttpDELETE executes HTTP DELETE request.
 func HttpDELETE(c Context, dc *doc.Context, path string, params interface{}, payload interface{}, result interface{}, status int) {
-	httpCall(c, dc, httpDELETE, path, params, payload, result, status)
+	reporterOf(c).Helper()
+	failOnError(c, TryHttpDELETE(c, dc, path, params, payload, result, status))
 }
 
-// Function httpCall executes HTTP request with specified HTTP method and parameters.
-func httpCall(c Context, dc *doc.Context, method string, path string, params interface{}, payload interface{}, result interface{}, status int) {
-	var req *http.Request
-	var requestBody []byte
-	var responseBody []byte
-	var err error
-	requestPath, err := prepareRequestPath(path, params)
-	common.PanicOnError(err)
-	uri := prepareUri(c, requestPath)
-	displayRequestDetails(c, method, uri)
-	if common.NilValue(payload) {
-		requestBody = nil
-		displayRequestPayload(c, nil)
-		req, err = http.NewRequest(method, uri, nil)
-		common.PanicOnError(err)
-	} else {
-		requestBody, err = json.Marshal(payload)
-		common.PanicOnError(err)
-		displayRequestPayload(c, requestBody)
-		req, err = http.NewRequest(method, uri, bytes.NewReader(requestBody))
-		common.PanicOnError(err)
-		req.Header.Add("Content-Type", "application/json")
-	}
-	setRequestHeaders(c, req)
-	client := http.Client{}
-	res, err := client.Do(req)
-	common.PanicOnError(err)
-	panicOnUnexpectedStatusCode(c, status, res)
-	if common.NilValue(result) {
-		responseBody = nil
-	} else {
-		responseBody = readResponseBody(c, res)
-		err = json.Unmarshal(responseBody, result)
-		common.PanicOnError(err)
-	}
-	collectDocumentationData(c, dc, res, method, path, requestPath, params, payload, result, requestBody, responseBody)
+// Function HttpPATCH executes HTTP PATCH request.
+func HttpPATCH(c Context, dc *doc.Context, path string, payload interface{}, result interface{}, status int) {
+	reporterOf(c).Helper()
+	failOnError(c, TryHttpPATCH(c, dc, path, payload, result, status))
+}
+
+// Function HttpHEAD executes HTTP HEAD request. The response to HEAD request has no body.
+func HttpHEAD(c Context, dc *doc.Context, path string, params interface{}, status int) {
+	reporterOf(c).Helper()
+	failOnError(c, TryHttpHEAD(c, dc, path, params, status))
+}
+
+// Function HttpOPTIONS executes HTTP OPTIONS request.
+func HttpOPTIONS(c Context, dc *doc.Context, path string, params interface{}, result interface{}, status int) {
+	reporterOf(c).Helper()
+	failOnError(c, TryHttpOPTIONS(c, dc, path, params, result, status))
+}
+
+// Function TryHttpGETString executes HTTP GET request and returns simple text result (not JSON string!).
+// Instead of breaking the execution, the failure is returned as *Error.
+func TryHttpGETString(c Context, dc *doc.Context, path string, params interface{}, result interface{}, status int) error {
+	_, err := New(c, dc).Get(path).Params(params).IntoText(result).Expect(status).TryDo()
+	return err
+}
+
+// Function TryHttpGET executes HTTP GET request and returns JSON result.
+// Instead of breaking the execution, the failure is returned as *Error.
+func TryHttpGET(c Context, dc *doc.Context, path string, params interface{}, result interface{}, status int) error {
+	return tryHttpCall(c, dc, httpGET, path, params, nil, result, status)
 }
 
-func collectDocumentationData(c Context, dc *doc.Context, res *http.Response, method string, path string, requestPath string, params interface{}, payload interface{}, result interface{}, requestBody []byte, responseBody []byte) {
+// Function TryHttpPOST executes HTTP POST request.
+// Instead of breaking the execution, the failure is returned as *Error.
+func TryHttpPOST(c Context, dc *doc.Context, path string, payload interface{}, result interface{}, status int) error {
+	return tryHttpCall(c, dc, httpPOST, path, nil, payload, result, status)
+}
+
+// Function TryHttpPUT executes HTTP PUT request.
+// Instead of breaking the execution, the failure is returned as *Error.
+func TryHttpPUT(c Context, dc *doc.Context, path string, payload interface{}, result interface{}, status int) error {
+	return tryHttpCall(c, dc, httpPUT, path, nil, payload, result, status)
+}
+
+// Function TryHttpDELETE executes HTTP DELETE request.
+// Instead of breaking the execution, the failure is returned as *Error.
+func TryHttpDELETE(c Context, dc *doc.Context, path string, params interface{}, payload interface{}, result interface{}, status int) error {
+	return tryHttpCall(c, dc, httpDELETE, path, params, payload, result, status)
+}
+
+// Function TryHttpPATCH executes HTTP PATCH request.
+// Instead of breaking the execution, the failure is returned as *Error.
+func TryHttpPATCH(c Context, dc *doc.Context, path string, payload interface{}, result interface{}, status int) error {
+	return tryHttpCall(c, dc, httpPATCH, path, nil, payload, result, status)
+}
+
+// Function TryHttpHEAD executes HTTP HEAD request. The response to HEAD request has no body.
+// Instead of breaking the execution, the failure is returned as *Error.
+func TryHttpHEAD(c Context, dc *doc.Context, path string, params interface{}, status int) error {
+	return tryHttpCall(c, dc, httpHEAD, path, params, nil, nil, status)
+}
+
+// Function TryHttpOPTIONS executes HTTP OPTIONS request.
+// Instead of breaking the execution, the failure is returned as *Error.
+func TryHttpOPTIONS(c Context, dc *doc.Context, path string, params interface{}, result interface{}, status int) error {
+	return tryHttpCall(c, dc, httpOPTIONS, path, params, nil, result, status)
+}
+
+// Function tryHttpCall executes HTTP request with specified HTTP method and parameters.
+// Any failure is returned as *Error.
+func tryHttpCall(c Context, dc *doc.Context, method string, path string, params interface{}, payload interface{}, result interface{}, status int) error {
+	_, err := New(c, dc).Method(method, path).Params(params).Body(payload).Into(result).Expect(status).TryDo()
+	return err
+}
+
+// Function collectDocumentationData saves the details of executed request
+// in documentation context, depending on the documentation collecting mode.
+// Requests executed without documentation context are not documented.
+func collectDocumentationData(cl *call) {
+	dc := cl.dc
+	if dc == nil {
+		return
+	}
 	if endpoint := dc.GetEndpoint(); endpoint != nil && dc.CollectDescriptionMode() {
-		endpoint.Method = method
-		endpoint.UrlRoot = c.GetUrl()
-		endpoint.UrlPath = path
-		if common.NilValue(params) {
+		endpoint.Method = cl.method
+		endpoint.UrlRoot = cl.c.GetUrl()
+		endpoint.UrlPath = cl.path
+		if common.NilValue(cl.params) {
 			endpoint.Parameters = nil
 		} else {
-			endpoint.Parameters = doc.ParseObject(params)
+			endpoint.Parameters = parameterFields(cl.params)
 		}
-		if common.NilValue(payload) {
+		if common.NilValue(cl.payload) {
 			endpoint.RequestBody = nil
+			endpoint.RequestMediaType = ""
 		} else {
-			endpoint.RequestBody = doc.ParseObject(payload)
+			switch cl.mediaType {
+			case MediaTypeMultipart, MediaTypeForm:
+				endpoint.RequestBody = formFields(cl.payload)
+			default:
+				endpoint.RequestBody = doc.ParseObject(cl.payload)
+			}
+			endpoint.RequestMediaType = cl.mediaType
 		}
-		if common.NilValue(result) {
+		if common.NilValue(cl.result) {
 			endpoint.ResponseBody = nil
 		} else {
-			endpoint.ResponseBody = doc.ParseObject(result)
+			endpoint.ResponseBody = doc.ParseObject(cl.result)
 		}
 	}
 	if endpoint := dc.GetEndpoint(); endpoint != nil && dc.CollectExamplesMode() {
@@ -153,49 +212,36 @@ func collectDocumentationData(c Context, dc *doc.Context, res *http.Response, me
 			endpoint.Examples = make([]doc.Example, 0)
 		}
 		example := doc.Example{
-			Summary:      dc.GetExampleSummary(),
-			Description:  dc.GetExampleDescription(),
-			Method:       method,
-			Uri:          c.GetUrl() + requestPath,
-			StatusCode:   res.StatusCode,
-			RequestBody:  common.PrettyPrint(requestBody),
-			ResponseBody: common.PrettyPrint(responseBody)}
+			Summary:         dc.GetExampleSummary(),
+			Description:     dc.GetExampleDescription(),
+			Method:          cl.method,
+			Uri:             cl.uri,
+			StatusCode:      cl.res.StatusCode,
+			ResponseHeaders: cl.res.Header.Clone(),
+			Timing:          cl.timing,
+			RequestHeaders:  redactSecrets(cl.sentHeader),
+			Curl:            curlCommand(cl),
+			RequestBody:     cl.requestText,
+			ResponseBody:    responseCodec(cl.res).Indent(cl.responseBody)}
 		endpoint.Examples = append(endpoint.Examples, example)
 	}
-	dc.SaveRole(method, path, res.StatusCode)
+	status := cl.res.StatusCode
+	if cl.method == MethodWS && status == http.StatusSwitchingProtocols {
+		// opened WebSocket channel means the access was granted
+		status = http.StatusOK
+	}
+	dc.SaveRole(cl.method, cl.path, status)
 	dc.StopCollecting()
 }
 
-func prepareRequestPath(path string, params interface{}) (string, error) {
-	if common.NilValue(params) {
-		return path, nil
-	}
-	paramsType := common.TypeOfValue(params)
-	if paramsType.Kind().String() != "struct" {
-		return "", errors.New("only struct parameters are allowed")
-	}
-	firstParameter := true
-	for i := 0; i < paramsType.NumField(); i++ {
-		field := paramsType.Field(i)
-		fieldJsonName := field.Tag.Get(common.JsonTagName)
-		placeholder := "{" + fieldJsonName + "}"
-		value := common.ValueOfValue(params).Field(i).Interface()
-		if !common.NilValue(value) {
-			valueStr := url.PathEscape(fmt.Sprintf("%v", common.ValueOfValue(value)))
-			if strings.Contains(path, placeholder) {
-				path = strings.ReplaceAll(path, placeholder, valueStr)
-			} else {
-				if firstParameter {
-					path = path + "?"
-				} else {
-					path = path + "&"
-				}
-				path = path + fieldJsonName + "=" + valueStr
-				firstParameter = false
-			}
-		}
+// Function acceptsPayload returns true when requests with specified method
+// are executed with payload passed in the request body.
+func acceptsPayload(method string) bool {
+	switch method {
+	case httpPOST, httpPUT, httpPATCH, httpDELETE:
+		return true
 	}
-	return path, nil
+	return false
 }
 
 // Function setRequestHeaders adds to the request authorization header and user defined headers.
@@ -211,13 +257,17 @@ func setRequestHeaders(c Context, req *http.Request) {
 }
 
 // Function readResponseBody reads and returns the body of HTTP response.
-func readResponseBody(c Context, res *http.Response) []byte {
+func readResponseBody(c Context, res *http.Response) ([]byte, error) {
 	body, err := ioutil.ReadAll(res.Body)
-	common.PanicOnError(err)
-	err = res.Body.Close()
-	common.PanicOnError(err)
-	displayResponseBody(c, body)
-	return body
+	if err != nil {
+		_ = res.Body.Close()
+		return nil, err
+	}
+	if err = res.Body.Close(); err != nil {
+		return nil, err
+	}
+	displayResponseBody(c, res.Header.Get("Content-Type"), body)
+	return body, nil
 }
 
 // Function prepareUri concatenates URL defined in context with
@@ -235,45 +285,57 @@ func displayRequestDetails(c Context, method string, uri string) {
 }
 
 // Function displayRequestPayload writes to standard output
-// pretty-printed request payload.
-func displayRequestPayload(c Context, payload []byte) {
+// request payload pretty-printed according to its content type.
+func displayRequestPayload(c Context, contentType string, payload []byte) {
 	if c.GetVerbose() {
 		if payload == nil {
 			fmt.Printf("\n===> REQUEST PAYLOAD:\n(none)\n")
 		} else {
-			fmt.Printf("\n===> REQUEST PAYLOAD:\n%s\n", common.PrettyPrint(payload))
+			fmt.Printf("\n===> REQUEST PAYLOAD:\n%s\n", common.PrettyPrintAs(contentType, payload))
 		}
 	}
 }
 
-// Function displayResponseBody writes to standard output
-// pretty-printed response body when verbose mode is on.
-func displayResponseBody(c Context, body []byte) {
+// Function displayResponseBody writes to standard output response body
+// pretty-printed according to its content type when verbose mode is on.
+func displayResponseBody(c Context, contentType string, body []byte) {
 	if c.GetVerbose() {
 		if body == nil {
 			fmt.Printf("\n<=== RESPONSE BODY:\n(none)\n")
 		} else {
-			fmt.Printf("\n<=== RESPONSE BODY:\n%s\n", common.PrettyPrint(body))
+			fmt.Printf("\n<=== RESPONSE BODY:\n%s\n", common.PrettyPrintAs(contentType, body))
 		}
 	}
 }
 
-// Function panicOnUnexpectedStatusCode displays error message and panics when
-// actual HTTP response status code differs from the expected one.
-func panicOnUnexpectedStatusCode(c Context, expectedCode int, res *http.Response) {
-	// display the returned status code if the same as expected
-	if c.GetVerbose() {
-		fmt.Printf("\n<=== STATUS:\n%d\n", res.StatusCode)
+// Function failOnError reports the failure of the request using
+// the reporter of the request context and stops the execution.
+func failOnError(c Context, err error) {
+	if err == nil {
+		return
 	}
-	// check if the expected status code is the same as returned by server
-	if res.StatusCode != expectedCode {
-		readResponseBody(c, res)
-		separator := common.MakeString('-', 120)
-		fmt.Printf("\n\n%s\n>     ERROR: unexpected status code\n>  Expected: %d\n>    Actual: %d\n%s\n\n",
-			separator,
-			expectedCode,
-			res.StatusCode,
-			separator)
-		common.BrExit()
+	r := reporterOf(c)
+	r.Helper()
+	var e *Error
+	if errors.As(err, &e) && e.UnexpectedStatus() {
+		r.Errorf(">     ERROR: unexpected status code\n>   Request: %s %s\n>  Expected: %d\n>    Actual: %d",
+			e.Method,
+			e.Uri,
+			e.ExpectedStatus,
+			e.ActualStatus)
+	} else if errors.As(err, &e) && e.MaxDuration > 0 {
+		r.Errorf(">     ERROR: request too slow\n>   Request: %s %s\n>  Expected: at most %v\n>    Actual: %v",
+			e.Method,
+			e.Uri,
+			e.MaxDuration,
+			e.Duration)
+	} else if errors.As(err, &e) && e.Timeout > 0 {
+		r.Errorf(">     ERROR: request timed out\n>   Request: %s %s\n>   Timeout: %v",
+			e.Method,
+			e.Uri,
+			e.Timeout)
+	} else {
+		r.Errorf(">     ERROR: request failed\n>     Cause: %v", err)
 	}
+	r.FailNow()
 }
@@ -1,7 +1,23 @@
 package rest
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/wisbery/oxyde/common"
+	"github.com/wisbery/oxyde/doc"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestSingleParameterInjection(t *testing.T) {
@@ -93,3 +109,1299 @@ func TestEmptyParameterAppend(t *testing.T) {
 		t.Error("empty parameter not appended")
 	}
 }
+
+func TestSliceParameterAppend(t *testing.T) {
+	path := "/users"
+	params := struct {
+		Ids   []int    `json:"ids"`
+		Roles []string `query:"roles,comma"`
+	}{
+		Ids:   []int{1, 2},
+		Roles: []string{"admin", "user"}}
+	requestPath, err := prepareRequestPath(path, params)
+	if requestPath != "/users?ids=1&ids=2&roles=admin%2Cuser" || err != nil {
+		t.Errorf("slice parameters not appended: %s", requestPath)
+	}
+}
+
+func TestOmitEmptyParameter(t *testing.T) {
+	path := "/users"
+	params := struct {
+		Name  string  `json:"name,omitempty"`
+		Age   int     `query:"age,omitempty"`
+		Email *string `json:"email"`
+		Skip  string  `query:"-"`
+	}{
+		Age:  32,
+		Skip: "skipped"}
+	requestPath, err := prepareRequestPath(path, params)
+	if requestPath != "/users?age=32" || err != nil {
+		t.Errorf("empty parameters not omitted: %s", requestPath)
+	}
+}
+
+func TestTimeParameterAppend(t *testing.T) {
+	path := "/events"
+	params := struct {
+		Since time.Time `json:"since"`
+	}{
+		Since: time.Date(2021, 3, 14, 15, 9, 26, 0, time.UTC)}
+	requestPath, err := prepareRequestPath(path, params)
+	if requestPath != "/events?since=2021-03-14T15%3A09%3A26Z" || err != nil {
+		t.Errorf("time parameter not appended: %s", requestPath)
+	}
+}
+
+type testPaging struct {
+	Page int `json:"page"`
+	Size int `json:"size"`
+}
+
+func TestStructParametersAppend(t *testing.T) {
+	path := "/users/{userId}/orders?sort=date"
+	params := struct {
+		testPaging
+		UserId string `json:"userId"`
+		Filter struct {
+			City string `json:"city"`
+		} `json:"filter"`
+	}{
+		testPaging: testPaging{Page: 2, Size: 10},
+		UserId:     "john doe"}
+	params.Filter.City = "Berlin"
+	requestPath, err := prepareRequestPath(path, params)
+	if requestPath != "/users/john%20doe/orders?sort=date&page=2&size=10&filter.city=Berlin" || err != nil {
+		t.Errorf("struct parameters not appended: %s", requestPath)
+	}
+}
+
+func TestParameterFields(t *testing.T) {
+	params := struct {
+		testPaging
+		UserId string    `json:"userId" api:"User identifier."`
+		Since  time.Time `query:"since" api:"?Start of the period."`
+		Hidden string    `json:"-"`
+		Filter *struct {
+			City string `json:"city"`
+		} `json:"filter"`
+	}{}
+	fields := parameterFields(params)
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		names = append(names, field.JsonName)
+	}
+	if strings.Join(names, " ") != "page size userId since filter.city" {
+		t.Errorf("unexpected parameter names: %v", names)
+	}
+	if fields[3].JsonType != "string" || fields[3].Mandatory || len(fields[3].Children) != 0 {
+		t.Errorf("unexpected time parameter documentation: %+v", fields[3])
+	}
+}
+
+func TestUnresolvedPlaceholder(t *testing.T) {
+	path := "/users/{userId}/orders/{orderId}"
+	params := struct {
+		UserId string `json:"userId"`
+	}{
+		UserId: "1"}
+	if _, err := prepareRequestPath(path, params); err == nil || !strings.Contains(err.Error(), "{orderId}") {
+		t.Errorf("unresolved placeholder not reported: %v", err)
+	}
+	if _, err := prepareRequestPath(path, nil); err == nil {
+		t.Error("unresolved placeholder without parameters not reported")
+	}
+}
+
+type testContext struct {
+	url string
+}
+
+func (c *testContext) GetUrl() string                { return c.url }
+func (c *testContext) GetAuthorizationToken() string { return "" }
+func (c *testContext) GetHeaders() map[string]string { return nil }
+func (c *testContext) GetVerbose() bool              { return false }
+
+func TestVerboseXmlBody(t *testing.T) {
+	body := common.PrettyPrintAs(MediaTypeXml+"; charset=utf-8", []byte(`<user><id>1</id></user>`))
+	if body != "<user>\n  <id>1</id>\n</user>" {
+		t.Errorf("unexpected pretty-printed body: %s", body)
+	}
+}
+
+type testUser struct {
+	Id   string `json:"id" api:"User identifier."`
+	Name string `json:"name" api:"User name."`
+}
+
+func TestTryHttpGET(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"id":"`+strings.TrimPrefix(r.URL.Path, "/users/")+`","name":"John"}`)
+	}))
+	defer server.Close()
+	c := &testContext{url: server.URL}
+	result := testUser{}
+	params := struct {
+		UserId string `json:"userId"`
+	}{UserId: "42"}
+	if err := TryHttpGET(c, doc.CreateDocContext(), "/users/{userId}", params, &result, 200); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Id != "42" || result.Name != "John" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestTryHttpPOSTUnexpectedStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, `{"error":"invalid name"}`)
+	}))
+	defer server.Close()
+	c := &testContext{url: server.URL}
+	err := TryHttpPOST(c, doc.CreateDocContext(), "/users", testUser{Name: "John"}, nil, 201)
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("expected *Error, got: %v", err)
+	}
+	if !e.UnexpectedStatus() || e.ExpectedStatus != 201 || e.ActualStatus != 400 {
+		t.Errorf("unexpected status details: %+v", e)
+	}
+	if e.Method != "POST" || e.Uri != server.URL+"/users" || string(e.ResponseBody) != `{"error":"invalid name"}` {
+		t.Errorf("unexpected request details: %+v", e)
+	}
+}
+
+func TestTryHttpDELETETransportError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+	c := &testContext{url: server.URL}
+	err := TryHttpDELETE(c, doc.CreateDocContext(), "/users/1", nil, nil, nil, 204)
+	var e *Error
+	if !errors.As(err, &e) || e.Err == nil || e.UnexpectedStatus() {
+		t.Errorf("expected transport error, got: %v", err)
+	}
+}
+
+func TestTryHttpPUTDecodeError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `not a json`)
+	}))
+	defer server.Close()
+	c := &testContext{url: server.URL}
+	result := testUser{}
+	err := TryHttpPUT(c, doc.CreateDocContext(), "/users/1", testUser{Name: "John"}, &result, 200)
+	var e *Error
+	if !errors.As(err, &e) || e.Err == nil || e.ActualStatus != 200 || string(e.ResponseBody) != "not a json" {
+		t.Errorf("expected decode error, got: %v", err)
+	}
+}
+
+func TestFailedRequestStopsCollecting(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/setup" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	c := &testContext{url: server.URL}
+	dc := doc.CreateDocContext()
+	dc.NewEndpointDocumentation("a", "users", "Find user")
+	dc.CollectAll("Find user")
+	dc.CollectRole("admin")
+	if err := TryHttpGET(c, dc, "/users/1", nil, nil, 200); err == nil {
+		t.Fatal("expected unexpected status code error")
+	}
+	dc.NewEndpointDocumentation("b", "users", "Delete user")
+	if err := TryHttpPOST(c, dc, "/setup", nil, nil, 200); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if endpoint := dc.GetEndpoint(); endpoint.Method != "" || len(endpoint.Examples) != 0 {
+		t.Errorf("setup request documented after failed request: %+v", endpoint)
+	}
+	dc.CollectAll("Receive notifications")
+	if _, err := TryHttpStream(c, dc, "/notifications", nil, 200); err == nil {
+		t.Fatal("expected unexpected status code error")
+	}
+	if dc.CollectExamplesMode() || dc.CollectDescriptionMode() {
+		t.Error("documentation still collected after failed stream request")
+	}
+}
+
+type testReporter struct {
+	messages []string
+	failed   bool
+}
+
+func (r *testReporter) Helper() {}
+
+func (r *testReporter) Errorf(format string, args ...interface{}) {
+	r.messages = append(r.messages, fmt.Sprintf(format, args...))
+}
+
+func (r *testReporter) FailNow() {
+	r.failed = true
+}
+
+func TestWithReporterUnexpectedStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	reporter := &testReporter{}
+	c := WithReporter(&testContext{url: server.URL}, reporter)
+	HttpGET(c, doc.CreateDocContext(), "/users/1", nil, nil, 200)
+	if !reporter.failed || len(reporter.messages) != 1 {
+		t.Fatalf("expected single reported failure, got: %+v", reporter)
+	}
+	if !strings.Contains(reporter.messages[0], "unexpected status code") || !strings.Contains(reporter.messages[0], "404") {
+		t.Errorf("unexpected failure message: %s", reporter.messages[0])
+	}
+}
+
+func TestWithReporterSuccess(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	c := WithReporter(&testContext{url: server.URL}, t)
+	HttpGET(c, doc.CreateDocContext(), "/users/1", nil, nil, 200)
+}
+
+func TestPatchHeadOptions(t *testing.T) {
+	methods := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		if r.Method == http.MethodHead {
+			return
+		}
+		_, _ = io.WriteString(w, `{"id":"1","name":"John"}`)
+	}))
+	defer server.Close()
+	c := WithReporter(&testContext{url: server.URL}, t)
+	dc := doc.CreateDocContext()
+	result := testUser{}
+	dc.NewEndpointDocumentation("", "users", "Update user")
+	dc.CollectDescription()
+	HttpPATCH(c, dc, "/users/1", testUser{Name: "John"}, &result, 200)
+	dc.SaveEndpointDocumentation()
+	HttpHEAD(c, dc, "/users/1", nil, 200)
+	HttpOPTIONS(c, dc, "/users", nil, &result, 200)
+	if strings.Join(methods, " ") != "PATCH HEAD OPTIONS" {
+		t.Errorf("unexpected methods: %v", methods)
+	}
+	endpoints := dc.GetEndpoints()
+	if len(endpoints) != 1 || endpoints[0].Method != "PATCH" || len(endpoints[0].RequestBody) != 2 {
+		t.Errorf("unexpected documentation: %+v", endpoints)
+	}
+}
+
+type testTransport struct {
+	requests int
+}
+
+func (t *testTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests++
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"id":"7","name":"Mary"}`)),
+		Request:    req}, nil
+}
+
+func TestWithTransport(t *testing.T) {
+	transport := &testTransport{}
+	c := WithReporter(WithTransport(&testContext{url: "http://users.test"}, transport), t)
+	result := testUser{}
+	HttpGET(c, doc.CreateDocContext(), "/users/7", nil, &result, 200)
+	if transport.requests != 1 || result.Name != "Mary" {
+		t.Errorf("request not executed using custom transport, requests: %d, result: %+v", transport.requests, result)
+	}
+}
+
+func TestWithClientTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+	c := WithClient(&testContext{url: server.URL}, &http.Client{Timeout: 20 * time.Millisecond})
+	if err := TryHttpGET(c, doc.CreateDocContext(), "/users", nil, nil, 200); err == nil {
+		t.Error("expected client timeout error")
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	reporter := &testReporter{}
+	c := WithReporter(WithTimeout(&testContext{url: server.URL}, 50*time.Millisecond), reporter)
+	HttpGET(c, doc.CreateDocContext(), "/users", nil, nil, 200)
+	if !reporter.failed || len(reporter.messages) != 1 {
+		t.Fatalf("expected single reported failure, got: %+v", reporter)
+	}
+	if !strings.Contains(reporter.messages[0], "timed out") || !strings.Contains(reporter.messages[0], "50ms") || !strings.Contains(reporter.messages[0], "/users") {
+		t.Errorf("unexpected failure message: %s", reporter.messages[0])
+	}
+}
+
+func TestWithContextCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := TryHttpGET(WithContext(&testContext{url: server.URL}, ctx), doc.CreateDocContext(), "/users", nil, nil, 200)
+	var e *Error
+	if !errors.As(err, &e) || !errors.Is(err, context.Canceled) || e.Timeout != 0 {
+		t.Errorf("expected cancelled request, got: %v", err)
+	}
+}
+
+func TestDefaultTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	SetDefaultTimeout(30 * time.Millisecond)
+	defer SetDefaultTimeout(0)
+	err := TryHttpGET(&testContext{url: server.URL}, doc.CreateDocContext(), "/users", nil, nil, 200)
+	var e *Error
+	if !errors.As(err, &e) || e.Timeout != 30*time.Millisecond {
+		t.Errorf("expected timed out request, got: %v", err)
+	}
+}
+
+func TestWithInterceptors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"id":"`+r.Header.Get("X-Correlation-Id")+`","name":"John"}`)
+	}))
+	defer server.Close()
+	order := make([]string, 0)
+	correlation := func(req *http.Request, next Handler) (*http.Response, error) {
+		order = append(order, "correlation")
+		req.Header.Set("X-Correlation-Id", "c-1")
+		return next(req)
+	}
+	rewrite := func(req *http.Request, next Handler) (*http.Response, error) {
+		order = append(order, "rewrite")
+		res, err := next(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			return nil, err
+		}
+		_ = res.Body.Close()
+		res.Body = io.NopCloser(strings.NewReader(strings.Replace(string(body), "John", "Johnny", 1)))
+		return res, nil
+	}
+	c := WithInterceptors(WithReporter(&testContext{url: server.URL}, t), correlation)
+	c = WithInterceptors(c, rewrite)
+	result := testUser{}
+	HttpGET(c, doc.CreateDocContext(), "/users/1", nil, &result, 200)
+	if result.Id != "c-1" || result.Name != "Johnny" {
+		t.Errorf("request or response not intercepted: %+v", result)
+	}
+	if strings.Join(order, " ") != "correlation rewrite" {
+		t.Errorf("unexpected interceptors order: %v", order)
+	}
+}
+
+func TestInterceptorShortCircuit(t *testing.T) {
+	stub := func(req *http.Request, next Handler) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusNoContent,
+			Body:       io.NopCloser(strings.NewReader("")),
+			Request:    req}, nil
+	}
+	c := WithInterceptors(&testContext{url: "http://unreachable.test"}, stub)
+	if err := TryHttpDELETE(c, doc.CreateDocContext(), "/users/1", nil, nil, nil, 204); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestMultipartUpload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		file, header, err := r.FormFile("avatar")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		_, _ = fmt.Fprintf(w, `{"id":"%s","name":"%s:%s:%s"}`, r.FormValue("title"), header.Filename, header.Header.Get("Content-Type"), content)
+	}))
+	defer server.Close()
+	type upload struct {
+		Title  string      `form:"title" api:"Avatar title."`
+		Avatar common.File `json:"image" form:"avatar" api:"Avatar image."`
+	}
+	payload := upload{
+		Title:  "Me",
+		Avatar: common.File{Name: "me.png", ContentType: "image/png", Content: []byte("PNG")}}
+	c := WithReporter(&testContext{url: server.URL}, t)
+	dc := doc.CreateDocContext()
+	dc.NewEndpointDocumentation("", "users", "Upload avatar")
+	dc.CollectAll("Upload avatar image")
+	result := testUser{}
+	HttpPOST(c, dc, "/avatars", Multipart(payload), &result, 200)
+	dc.SaveEndpointDocumentation()
+	if result.Id != "Me" || result.Name != "me.png:image/png:PNG" {
+		t.Errorf("unexpected multipart request: %+v", result)
+	}
+	endpoint := dc.GetEndpoints()[0]
+	if len(endpoint.RequestBody) != 2 || endpoint.RequestBody[0].JsonName != "title" || endpoint.RequestBody[1].JsonName != "avatar" || endpoint.RequestBody[1].JsonType != "binary" {
+		t.Errorf("unexpected request body documentation: %+v", endpoint.RequestBody)
+	}
+	if endpoint.Examples[0].RequestBody != "title: Me\navatar: me.png (image/png, 3 bytes)" {
+		t.Errorf("unexpected example request body: %s", endpoint.Examples[0].RequestBody)
+	}
+	if !strings.HasSuffix(endpoint.Examples[0].Curl, "/avatars' -F 'title=Me' -F 'avatar=@me.png;type=image/png'") {
+		t.Errorf("unexpected example curl command: %s", endpoint.Examples[0].Curl)
+	}
+}
+
+func TestFormEncodedBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != MediaTypeForm || r.ParseForm() != nil {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"id":"%s","name":"%s"}`, r.PostForm.Get("grant_type"), strings.Join(r.PostForm["scope"], " "))
+	}))
+	defer server.Close()
+	type login struct {
+		GrantType string   `form:"grant_type" api:"Grant type."`
+		Username  string   `json:"username" api:"User name."`
+		Scopes    []string `form:"scope" api:"Requested scopes."`
+		Code      string   `form:"code,omitempty" api:"?Verification code."`
+	}
+	payload := login{GrantType: "password", Username: "john@example.com", Scopes: []string{"read", "write"}}
+	c := WithReporter(&testContext{url: server.URL}, t)
+	dc := doc.CreateDocContext()
+	dc.NewEndpointDocumentation("", "auth", "Login")
+	dc.CollectAll("Login with password")
+	result := testUser{}
+	HttpPOST(c, dc, "/token", Form(payload), &result, 200)
+	dc.SaveEndpointDocumentation()
+	if result.Id != "password" || result.Name != "read write" {
+		t.Errorf("unexpected form request: %+v", result)
+	}
+	endpoint := dc.GetEndpoints()[0]
+	if endpoint.RequestMediaType != MediaTypeForm || endpoint.RequestBody[1].JsonName != "username" {
+		t.Errorf("unexpected request body documentation: %+v", endpoint)
+	}
+	if endpoint.Examples[0].RequestBody != "grant_type=password&username=john%40example.com&scope=read&scope=write" {
+		t.Errorf("unexpected example request body: %s", endpoint.Examples[0].RequestBody)
+	}
+}
+
+type testXmlUser struct {
+	XMLName xml.Name `xml:"user"`
+	Id      string   `xml:"id" api:"User identifier."`
+	Name    string   `xml:"name" api:"User name."`
+}
+
+func TestXmlBodies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := testXmlUser{}
+		if r.Header.Get("Content-Type") != MediaTypeXml || xml.NewDecoder(r.Body).Decode(&user) != nil {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		_, _ = fmt.Fprintf(w, `<user><id>5</id><name>%s</name></user>`, user.Name)
+	}))
+	defer server.Close()
+	c := WithReporter(&testContext{url: server.URL}, t)
+	dc := doc.CreateDocContext()
+	dc.NewEndpointDocumentation("", "partners", "Create partner user")
+	dc.CollectAll("Create user")
+	result := testXmlUser{}
+	HttpPOST(c, dc, "/users", Xml(testXmlUser{Name: "John"}), &result, 200)
+	dc.SaveEndpointDocumentation()
+	if result.Id != "5" || result.Name != "John" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	endpoint := dc.GetEndpoints()[0]
+	if endpoint.RequestMediaType != MediaTypeXml || len(endpoint.ResponseBody) != 2 || endpoint.ResponseBody[0].JsonName != "id" {
+		t.Errorf("unexpected documentation: %+v", endpoint)
+	}
+	if endpoint.Examples[0].ResponseBody != "<user>\n  <id>5</id>\n  <name>John</name>\n</user>" {
+		t.Errorf("unexpected example response body: %s", endpoint.Examples[0].ResponseBody)
+	}
+}
+
+type testLinesCodec struct{}
+
+func (testLinesCodec) Marshal(v interface{}) ([]byte, error) {
+	return []byte(strings.Join(*v.(*[]string), "\n")), nil
+}
+
+func (testLinesCodec) Unmarshal(data []byte, v interface{}) error {
+	*v.(*[]string) = strings.Split(string(data), "\n")
+	return nil
+}
+
+func (testLinesCodec) Indent(data []byte) string {
+	return string(data)
+}
+
+func TestRegisteredCodec(t *testing.T) {
+	common.RegisterCodec("text/x-lines", testLinesCodec{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/x-lines")
+		_, _ = w.Write(bytes.ToUpper(body))
+	}))
+	defer server.Close()
+	c := WithReporter(&testContext{url: server.URL}, t)
+	payload := []string{"a", "b"}
+	result := make([]string, 0)
+	HttpPUT(c, doc.CreateDocContext(), "/lines", Encoded("text/x-lines", &payload), &result, 200)
+	if strings.Join(result, ",") != "A,B" {
+		t.Errorf("unexpected result: %v", result)
+	}
+}
+
+func TestWithResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/users/9")
+		w.Header().Set("X-Request-Id", "r-1")
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s-1"})
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	response := Response{}
+	c := WithResponse(WithReporter(&testContext{url: server.URL}, t), &response)
+	dc := doc.CreateDocContext()
+	dc.NewEndpointDocumentation("", "users", "Create user")
+	dc.CollectExamples("Create user", "")
+	HttpPOST(c, dc, "/users", testUser{Name: "John"}, nil, 201)
+	dc.SaveEndpointDocumentation()
+	if response.StatusCode != 201 || response.Header.Get("Location") != "/users/9" || response.Cookie("session").Value != "s-1" || response.Duration <= 0 {
+		t.Errorf("unexpected response metadata: %+v", response)
+	}
+	if dc.GetEndpoints()[0].Examples[0].ResponseHeaders.Get("X-Request-Id") != "r-1" {
+		t.Errorf("response headers not saved in example: %+v", dc.GetEndpoints()[0].Examples[0])
+	}
+	err := TryHttpGET(WithResponse(&testContext{url: server.URL}, &response), dc, "/users/9", nil, nil, 200)
+	if err == nil || response.StatusCode != 201 {
+		t.Errorf("response metadata not saved for failed request: %v, %+v", err, response)
+	}
+}
+
+func TestWithSessionAndCSRF(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s-1", Path: "/"})
+			http.SetCookie(w, &http.Cookie{Name: "XSRF-TOKEN", Value: "x-1", Path: "/"})
+		case "/users":
+			session, err := r.Cookie("session")
+			if err != nil || session.Value != "s-1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.Method == http.MethodPost && r.Header.Get("X-XSRF-TOKEN") != "x-1" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			if r.Method == http.MethodGet && r.Header.Get("X-XSRF-TOKEN") != "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+	plain := WithReporter(&testContext{url: server.URL}, t)
+	c := WithCSRF(WithSession(plain), "XSRF-TOKEN", "X-XSRF-TOKEN")
+	dc := doc.CreateDocContext()
+	HttpPOST(c, dc, "/login", nil, nil, 200)
+	HttpGET(c, dc, "/users", nil, nil, 204)
+	HttpPOST(c, dc, "/users", testUser{Name: "John"}, nil, 204)
+	HttpGET(plain, dc, "/users", nil, nil, 401)
+}
+
+func TestWithTokenProvider(t *testing.T) {
+	issued := 0
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientId, clientSecret, ok := r.BasicAuth()
+		if !ok || clientId != "app" || clientSecret != "secret" || r.FormValue("grant_type") != "client_credentials" || r.FormValue("scope") != "users" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		issued++
+		_, _ = fmt.Fprintf(w, `{"access_token":"t-%d","token_type":"bearer","expires_in":3600}`, issued)
+	}))
+	defer tokenServer.Close()
+	valid := "Bearer t-1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+	provider := ClientCredentials(tokenServer.URL, "app", "secret", "users")
+	c := WithTokenProvider(WithReporter(&testContext{url: server.URL}, t), provider)
+	dc := doc.CreateDocContext()
+	result := testUser{}
+	HttpGET(c, dc, "/users/1", nil, nil, 200)
+	HttpGET(c, dc, "/users/1", nil, nil, 200)
+	if issued != 1 {
+		t.Errorf("token not cached, issued: %d", issued)
+	}
+	valid = "Bearer t-2"
+	HttpPUT(c, dc, "/users/1", testUser{Id: "1", Name: "John"}, &result, 200)
+	if issued != 2 || result.Name != "John" {
+		t.Errorf("token not refreshed after 401 response, issued: %d, result: %+v", issued, result)
+	}
+}
+
+func TestPasswordGrant(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "password" || r.FormValue("username") != "john" || r.FormValue("password") != "pass" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"error":"invalid_grant"}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"access_token":"p-1","token_type":"Bearer"}`)
+	}))
+	defer tokenServer.Close()
+	token, err := PasswordGrant(tokenServer.URL, "app", "secret", "john", "pass").Token(context.Background())
+	if err != nil || token != "Bearer p-1" {
+		t.Errorf("unexpected token: %s, %v", token, err)
+	}
+	if _, err = PasswordGrant(tokenServer.URL, "app", "secret", "john", "wrong").Token(context.Background()); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("expected token request error, got: %v", err)
+	}
+}
+
+func TestShortLivedTokenCached(t *testing.T) {
+	issued := 0
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		issued++
+		_, _ = fmt.Fprintf(w, `{"access_token":"s-%d","token_type":"Bearer","expires_in":4}`, issued)
+	}))
+	defer tokenServer.Close()
+	provider := PasswordGrant(tokenServer.URL, "app", "secret", "john", "pass")
+	for i := 0; i < 3; i++ {
+		if token, err := provider.Token(context.Background()); err != nil || token != "Bearer s-1" {
+			t.Errorf("unexpected token: %s, %v", token, err)
+		}
+	}
+	if issued != 1 {
+		t.Errorf("short-lived token not cached, issued tokens: %d", issued)
+	}
+}
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "users.json")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s-1", Path: "/", HttpOnly: true})
+		_, _ = fmt.Fprintf(w, `{"id":"%s","name":"%s"}`, r.URL.Query().Get("userId"), r.Header.Get("Authorization"))
+	}))
+	recorder, err := NewCassette(path, CassetteRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := struct {
+		UserId string `json:"userId"`
+	}{UserId: "1"}
+	result := testUser{}
+	c := WithCassette(WithReporter(&testContext{url: server.URL}, t), recorder)
+	HttpGET(c, doc.CreateDocContext(), "/users", params, &result, 200)
+	if err = recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+	player, err := NewCassette(path, CassetteReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dc := doc.CreateDocContext()
+	dc.NewEndpointDocumentation("", "users", "Find user")
+	dc.CollectAll("Find user")
+	response := Response{}
+	c = WithResponse(WithCassette(WithReporter(&testContext{url: server.URL}, t), player), &response)
+	result = testUser{}
+	HttpGET(c, dc, "/users", params, &result, 200)
+	dc.SaveEndpointDocumentation()
+	if result.Id != "1" {
+		t.Errorf("unexpected replayed result: %+v", result)
+	}
+	if cookie := response.Cookie("session"); cookie == nil || cookie.Value != "<redacted>" || !cookie.HttpOnly {
+		t.Errorf("unexpected replayed cookie: %v", cookie)
+	}
+	if dc.GetEndpoints()[0].Examples[0].ResponseBody == "" {
+		t.Error("documentation not collected from replayed response")
+	}
+	params.UserId = "2"
+	if err = TryHttpGET(c, dc, "/users", params, &result, 200); err == nil || !strings.Contains(err.Error(), "no recorded interaction") {
+		t.Errorf("expected missing interaction error, got: %v", err)
+	}
+	keeper := &Cassette{KeepCookies: true}
+	if header := keeper.responseHeader(&http.Response{Header: http.Header{"Set-Cookie": {"session=s-1"}}}); header.Get("Set-Cookie") != "session=s-1" {
+		t.Errorf("unexpected recorded cookie: %v", header)
+	}
+}
+
+func TestCassetteRecordMissing(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+	cassette, err := NewCassette(filepath.Join(t.TempDir(), "missing.json"), CassetteRecordMissing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cassette.Match = MatchAll
+	c := WithCassette(WithReporter(&testContext{url: server.URL}, t), cassette)
+	dc := doc.CreateDocContext()
+	HttpPOST(c, dc, "/users", testUser{Name: "John"}, nil, 200)
+	HttpPOST(c, dc, "/users", testUser{Name: "John"}, nil, 200)
+	HttpPOST(c, dc, "/users", testUser{Name: "Mary"}, nil, 200)
+	if requests != 2 || len(cassette.Interactions()) != 2 {
+		t.Errorf("unexpected requests: %d, interactions: %d", requests, len(cassette.Interactions()))
+	}
+}
+
+func TestParallelDocumentationScopes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"id":"%s","name":"John"}`, strings.TrimPrefix(r.URL.Path, "/users/"))
+	}))
+	defer server.Close()
+	dc := doc.CreateDocContext()
+	t.Run("users", func(t *testing.T) {
+		for i := 0; i < 10; i++ {
+			id := fmt.Sprintf("%d", i)
+			t.Run(id, func(t *testing.T) {
+				t.Parallel()
+				scope := dc.NewScope()
+				scope.NewEndpointDocumentation(id, "users", "Find user "+id)
+				scope.CollectAll("Find user " + id)
+				result := testUser{}
+				HttpGET(WithReporter(&testContext{url: server.URL}, t), scope, "/users/"+id, nil, &result, 200)
+				scope.SaveEndpointDocumentation()
+			})
+		}
+	})
+	for _, endpoint := range dc.GetEndpoints() {
+		if len(endpoint.Examples) != 1 || endpoint.Examples[0].Uri != server.URL+"/users/"+endpoint.Id {
+			t.Errorf("documentation of endpoint %s mixed with other scopes: %+v", endpoint.Id, endpoint.Examples)
+		}
+	}
+}
+
+func TestTimingAndMaxDuration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(60 * time.Millisecond)
+		}
+		_, _ = io.WriteString(w, `{"id":"1","name":"John"}`)
+	}))
+	defer server.Close()
+	response := Response{}
+	dc := doc.CreateDocContext()
+	dc.NewEndpointDocumentation("", "users", "Find user")
+	dc.CollectExamples("Find user", "")
+	c := WithMaxDuration(WithResponse(WithReporter(&testContext{url: server.URL}, t), &response), time.Second)
+	HttpGET(c, dc, "/slow", nil, &testUser{}, 200)
+	dc.SaveEndpointDocumentation()
+	timing := dc.GetEndpoints()[0].Examples[0].Timing
+	if timing.Total < 60*time.Millisecond || timing.TTFB < 60*time.Millisecond || timing.TTFB > timing.Total || response.Timing != timing {
+		t.Errorf("unexpected timing: %+v, response timing: %+v", timing, response.Timing)
+	}
+	reporter := &testReporter{}
+	c = WithEndpointMaxDuration(WithMaxDuration(WithReporter(&testContext{url: server.URL}, reporter), time.Second), "GET", "/slow", 20*time.Millisecond)
+	HttpGET(c, doc.CreateDocContext(), "/fast", nil, &testUser{}, 200)
+	HttpGET(c, doc.CreateDocContext(), "/slow", nil, &testUser{}, 200)
+	if len(reporter.messages) != 1 || !strings.Contains(reporter.messages[0], "too slow") || !strings.Contains(reporter.messages[0], "at most 20ms") {
+		t.Errorf("unexpected failure messages: %v", reporter.messages)
+	}
+	err := TryHttpGET(WithMaxDuration(&testContext{url: server.URL}, 20*time.Millisecond), doc.CreateDocContext(), "/slow", nil, nil, 200)
+	var e *Error
+	if !errors.As(err, &e) || e.MaxDuration != 20*time.Millisecond || e.Duration < 60*time.Millisecond || e.UnexpectedStatus() {
+		t.Errorf("expected too slow request error, got: %v", err)
+	}
+}
+
+type testAccount struct {
+	testPaging
+	Id      string `json:"id"`
+	Owner   *testUser
+	Tags    []testTag          `json:"tags"`
+	Limits  map[string]testTag `json:"limits"`
+	Ignored string             `json:"-"`
+}
+
+type testTag struct {
+	Name string `json:"name"`
+}
+
+func TestStrictDecoding(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id":"1","page":1,"owner":{"name":"John","nick":"j"},"tags":[{"name":"a"},{"name":"b","color":"red"}],"limits":{"daily":{"name":"d","max":5}},"Ignored":"x","extra":true}`)
+	}))
+	defer server.Close()
+	dc := doc.CreateDocContext()
+	c := &testContext{url: server.URL}
+	account := testAccount{}
+	if err := TryHttpGET(c, dc, "/accounts/1", nil, &account, 200); err != nil {
+		t.Fatalf("lenient decoding failed: %v", err)
+	}
+	if err := TryHttpGET(WithStrictDecoding(c, StrictWarn), dc, "/accounts/1", nil, &account, 200); err != nil {
+		t.Fatalf("warning mode failed the request: %v", err)
+	}
+	err := TryHttpGET(WithStrictDecoding(c, StrictFail), dc, "/accounts/1", nil, &account, 200)
+	var unknown *UnknownFieldsError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected unknown fields error, got: %v", err)
+	}
+	expected := "$.Ignored, $.extra, $.limits.daily.max, $.owner.nick, $.tags[1].color"
+	if strings.Join(unknown.Paths, ", ") != expected {
+		t.Errorf("unexpected unknown fields: %v", unknown.Paths)
+	}
+	SetStrictDecoding(StrictFail)
+	defer SetStrictDecoding(StrictOff)
+	if err := TryHttpGET(c, dc, "/accounts/1", nil, &account, 200); !errors.As(err, &unknown) {
+		t.Errorf("expected unknown fields error with default strict mode, got: %v", err)
+	}
+	if err := TryHttpGET(WithStrictDecoding(c, StrictOff), dc, "/accounts/1", nil, &account, 200); err != nil {
+		t.Errorf("strict decoding not disabled for the call: %v", err)
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		_, _ = io.WriteString(w, ": connected\n\nid: 1\nevent: created\ndata: {\"id\":\"u1\",\ndata: \"name\":\"John\"}\n\n")
+		w.(http.Flusher).Flush()
+		<-release
+		_, _ = io.WriteString(w, "id: 2\nevent: deleted\ndata: u1\n\n")
+	}))
+	defer server.Close()
+	defer close(release)
+	c := WithReporter(&testContext{url: server.URL}, t)
+	dc := doc.CreateDocContext()
+	dc.NewEndpointDocumentation("", "notifications", "Notifications")
+	dc.CollectAll("Receive notifications")
+	s := HttpStream(c, dc, "/notifications", nil, 200)
+	event := s.Next(time.Second)
+	user := testUser{}
+	if event.Id != "1" || event.Event != "created" || event.Decode(&user) != nil || user.Name != "John" {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	if _, err := s.TryNext(20 * time.Millisecond); err == nil || !strings.Contains(err.Error(), "no event received within 20ms") {
+		t.Errorf("expected event timeout, got: %v", err)
+	}
+	release <- struct{}{}
+	s.Expect(time.Second, Event{Event: "deleted", Data: "u1"})
+	s.Close()
+	dc.SaveEndpointDocumentation()
+	example := dc.GetEndpoints()[0].Examples[0]
+	expected := "id: 1\nevent: created\ndata: {\"id\":\"u1\",\ndata: \"name\":\"John\"}\n\nid: 2\nevent: deleted\ndata: u1"
+	if example.ResponseBody != expected {
+		t.Errorf("unexpected stream sample: %q", example.ResponseBody)
+	}
+}
+
+func TestNdjsonStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", MediaTypeNdjson)
+		_, _ = io.WriteString(w, "{\"id\":\"1\"}\n\n{\"id\":\"2\"}\n")
+	}))
+	defer server.Close()
+	c := &testContext{url: server.URL}
+	s, err := TryHttpStream(c, doc.CreateDocContext(), "/users", nil, 200)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+	if err = s.TryExpect(time.Second, Event{Data: `{"id":"1"}`}, Event{Data: `{"id":"2"}`}); err != nil {
+		t.Errorf("unexpected events: %v", err)
+	}
+	if _, err = s.TryNext(time.Second); !errors.Is(err, io.EOF) {
+		t.Errorf("expected end of stream, got: %v", err)
+	}
+	if _, err = TryHttpStream(c, doc.CreateDocContext(), "/users", nil, 201); err == nil {
+		t.Error("expected unexpected status code error")
+	}
+}
+
+func TestCassetteStream(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", MediaTypeEventStream)
+		_, _ = fmt.Fprintf(w, "data: %s\n\n", r.Header.Get("Accept"))
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+	path := filepath.Join(t.TempDir(), "stream.json")
+	for _, mode := range []int{CassetteRecord, CassetteReplay} {
+		cassette, err := NewCassette(path, mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := WithCassette(WithReporter(&testContext{url: server.URL}, t), cassette)
+		s := HttpStream(c, nil, "/notifications", nil, 200)
+		s.Expect(time.Second, Event{Data: MediaTypeEventStream + ", " + MediaTypeNdjson})
+		s.Close()
+		if len(cassette.Interactions()) != 0 {
+			t.Errorf("unexpected recorded interactions: %+v", cassette.Interactions())
+		}
+		if err = cassette.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+type testTokenContext struct {
+	*testContext
+	token string
+}
+
+func (c *testTokenContext) GetAuthorizationToken() string { return c.token }
+
+func TestWebSocket(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ws" || r.Header.Get("Upgrade") != "websocket" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		conn, rw, _ := w.(http.Hijacker).Hijack()
+		defer conn.Close()
+		_, _ = fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", acceptKey(r.Header.Get("Sec-WebSocket-Key")))
+		// greeting split into two fragments, preceded by ping
+		_, _ = rw.Write([]byte{0x89, 0x01, 'p', 0x01, 0x0D})
+		_, _ = rw.WriteString(`{"id":"0","na`)
+		_, _ = rw.Write([]byte{0x80, 0x0E})
+		_, _ = rw.WriteString(`me":"Welcome"}`)
+		_ = rw.Flush()
+		for {
+			_, opcode, payload, err := readFrame(rw.Reader)
+			if err != nil {
+				return
+			}
+			if opcode == opPong {
+				continue
+			}
+			_, _ = rw.Write([]byte{0x80 | opcode, byte(len(payload))})
+			_, _ = rw.Write(payload)
+			_ = rw.Flush()
+			if opcode == opClose {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+	tc := &testContext{url: server.URL}
+	c := WithReporter(&testTokenContext{testContext: tc, token: "Bearer ws"}, t)
+	dc := doc.CreateDocContext()
+	dc.NewEndpointDocumentation("", "users", "User events")
+	dc.CollectAll("Exchange user messages")
+	dc.CollectRole("user")
+	ws := WsOpen(c, dc, "/users/{userId}/events", struct {
+		UserId string `json:"userId"`
+	}{UserId: "u1"})
+	ws.Expect(time.Second, testUser{Id: "0", Name: "Welcome"})
+	ws.Send(testUser{Id: "1", Name: "John"})
+	echo := testUser{}
+	ws.Receive(time.Second, &echo)
+	if echo.Name != "John" {
+		t.Errorf("unexpected message: %+v", echo)
+	}
+	if err := ws.TryReceive(20*time.Millisecond, &echo); err == nil || !strings.Contains(err.Error(), "no message received within 20ms") {
+		t.Errorf("expected message timeout, got: %v", err)
+	}
+	ws.Send(testUser{Id: "3"})
+	if err := ws.TryExpect(time.Second, testUser{Id: "2"}); err == nil || !strings.Contains(err.Error(), "unexpected message") {
+		t.Errorf("expected unexpected message error, got: %v", err)
+	}
+	ws.Close()
+	dc.SaveEndpointDocumentation()
+	endpoint := dc.GetEndpoints()[0]
+	if endpoint.Method != MethodWS || !strings.HasPrefix(endpoint.Examples[0].Uri, "ws://") || !strings.HasSuffix(endpoint.Examples[0].Uri, "/users/u1/events") {
+		t.Errorf("unexpected WebSocket documentation: %+v", endpoint)
+	}
+	if access := dc.GetAccess(MethodWS, "/users/{userId}/events", "user"); access != doc.AccessGranted {
+		t.Errorf("unexpected access: %d", access)
+	}
+	if len(endpoint.RequestBody) != 2 || len(endpoint.ResponseBody) != 2 || endpoint.Examples[0].StatusCode != 101 {
+		t.Errorf("unexpected message documentation: %+v", endpoint)
+	}
+	if !strings.Contains(endpoint.Examples[0].ResponseBody, "Welcome") || !strings.Contains(endpoint.Examples[0].RequestBody, "John") {
+		t.Errorf("unexpected exchanged messages: %+v", endpoint.Examples[0])
+	}
+	if _, err := TryWsOpen(tc, dc, "/users/u1/events", nil); err == nil {
+		t.Error("expected failed opening handshake")
+	}
+	cassette, err := NewCassette(filepath.Join(t.TempDir(), "ws.json"), CassetteRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mode := range []int{CassetteRecord, CassetteReplay} {
+		cassette.Mode = mode
+		ws = WsOpen(WithCassette(c, cassette), nil, "/users/u1/events", nil)
+		ws.Expect(time.Second, testUser{Id: "0", Name: "Welcome"})
+		ws.Close()
+	}
+	if len(cassette.Interactions()) != 0 {
+		t.Errorf("unexpected recorded interactions: %+v", cassette.Interactions())
+	}
+}
+
+func TestWebSocketFrameLimit(t *testing.T) {
+	frame := []byte{0x82, 127, 0x7F, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+	if _, _, _, err := readFrame(bufio.NewReader(bytes.NewReader(frame))); err == nil || !strings.Contains(err.Error(), "exceeds the limit") {
+		t.Errorf("expected frame size error, got: %v", err)
+	}
+}
+
+func TestGraphQL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := struct {
+			Query         string            `json:"query"`
+			OperationName string            `json:"operationName"`
+			Variables     map[string]string `json:"variables"`
+		}{}
+		if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&request) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if request.Variables["userId"] == "" {
+			_, _ = io.WriteString(w, `{"data":null,"errors":[{"message":"missing userId","locations":[{"line":1,"column":15}],"path":["user"]}],"hasNext":false}`)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"data":{"user":{"id":"%s","name":"John"}}}`, request.Variables["userId"])
+	}))
+	defer server.Close()
+	const query = `query GetUser($userId: ID!) { user(id: $userId) { id name } }`
+	type variables struct {
+		UserId string `json:"userId" api:"User identifier."`
+	}
+	result := struct {
+		User testUser `json:"user" api:"User details."`
+	}{}
+	c := WithReporter(&testContext{url: server.URL}, t)
+	dc := doc.CreateDocContext()
+	dc.NewEndpointDocumentation("", "users", "Get user")
+	dc.CollectAll("Get user details")
+	HttpGraphQL(c, dc, "/graphql", query, "", variables{UserId: "u1"}, &result)
+	dc.SaveEndpointDocumentation()
+	if result.User.Id != "u1" || result.User.Name != "John" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	endpoint := dc.GetEndpoints()[0]
+	if endpoint.Method != "POST" || endpoint.UrlPath != "/graphql#GetUser" || len(endpoint.Parameters) != 1 || endpoint.Parameters[0].JsonName != "userId" {
+		t.Errorf("unexpected operation documentation: %+v", endpoint)
+	}
+	if endpoint.RequestBody != nil || len(endpoint.ResponseBody) != 1 || !strings.HasPrefix(endpoint.Examples[0].RequestBody, "query GetUser") {
+		t.Errorf("unexpected operation body documentation: %+v", endpoint)
+	}
+	err := TryHttpGraphQL(WithStrictDecoding(c, StrictFail), doc.CreateDocContext(), "/graphql", query, "GetUser", variables{}, &result)
+	var errs GraphQLErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Message != "missing userId" || len(errs[0].Locations) != 1 || errs[0].Locations[0].Column != 15 {
+		t.Errorf("expected GraphQL errors, got: %v", err)
+	}
+}
+
+func TestCurlCommand(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	addApiKey := func(req *http.Request, next Handler) (*http.Response, error) {
+		req.Header.Set("X-Api-Key", "k-1")
+		req.Header.Set("X-Trace", "t-1")
+		return next(req)
+	}
+	tc := &testTokenContext{testContext: &testContext{url: server.URL}, token: "Bearer secret"}
+	c := WithInterceptors(WithReporter(tc, t), addApiKey)
+	dc := doc.CreateDocContext()
+	dc.NewEndpointDocumentation("", "users", "Create user")
+	dc.CollectExamples("Create user", "")
+	HttpPOST(c, dc, "/users", testUser{Name: "John O'Hara"}, nil, 201)
+	dc.SaveEndpointDocumentation()
+	example := dc.GetEndpoints()[0].Examples[0]
+	expected := "curl -X POST '" + server.URL + "/users'" +
+		" -H 'Authorization: Bearer <redacted>'" +
+		" -H 'Content-Type: application/json'" +
+		" -H 'X-Api-Key: <redacted>'" +
+		" -H 'X-Trace: t-1'" +
+		` --data-raw '{"id":"","name":"John O'\''Hara"}'`
+	if example.Curl != expected {
+		t.Errorf("unexpected curl command:\n%s\nexpected:\n%s", example.Curl, expected)
+	}
+	if example.RequestHeaders.Get("X-Trace") != "t-1" || example.RequestHeaders.Get("Authorization") != "Bearer <redacted>" {
+		t.Errorf("unexpected request headers: %v", example.RequestHeaders)
+	}
+}
+
+func TestHar(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s-1"})
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		_, _ = io.WriteString(w, `{"id":"1","name":"John"}`)
+	}))
+	har := NewHar(filepath.Join(t.TempDir(), "run", "requests.har"))
+	tc := &testTokenContext{testContext: &testContext{url: server.URL}, token: "Bearer secret"}
+	c := WithHar(tc, har)
+	user := testUser{}
+	if err := TryHttpGET(c, doc.CreateDocContext(), "/users/{userId}", struct {
+		UserId string `json:"userId"`
+		Full   bool   `json:"full"`
+	}{UserId: "1", Full: true}, &user, 200); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := TryHttpPOST(c, nil, "/users", testUser{Name: "John"}, nil, 200); err == nil {
+		t.Fatal("expected unexpected status code error")
+	}
+	server.Close()
+	if err := TryHttpGET(c, nil, "/users", nil, nil, 200); err == nil {
+		t.Fatal("expected connection error")
+	}
+	entries := har.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 recorded entries, got: %d", len(entries))
+	}
+	get, post, failed := entries[0], entries[1], entries[2]
+	if get.Request.Method != "GET" || get.Response.Status != 200 || get.Response.Content.Text != `{"id":"1","name":"John"}` || get.Response.Content.MimeType != "application/json" {
+		t.Errorf("unexpected GET entry: %+v", get)
+	}
+	if len(get.Request.QueryString) != 1 || get.Request.QueryString[0].Value != "true" || get.Time <= 0 || get.Timings.Wait < 0 {
+		t.Errorf("unexpected GET entry details: %+v", get)
+	}
+	if len(get.Response.Cookies) != 1 || get.Response.Cookies[0].Value != "<redacted>" {
+		t.Errorf("unexpected GET entry cookies: %+v", get.Response.Cookies)
+	}
+	for _, header := range get.Request.Headers {
+		if header.Name == "Authorization" && header.Value != "Bearer <redacted>" {
+			t.Errorf("authorization header not redacted: %s", header.Value)
+		}
+	}
+	if post.Response.Status != 201 || post.Request.PostData == nil || post.Request.PostData.Text != `{"id":"","name":"John"}` {
+		t.Errorf("unexpected POST entry: %+v", post)
+	}
+	if failed.Response.Status != 0 || failed.Error == "" {
+		t.Errorf("unexpected failed entry: %+v", failed)
+	}
+	if err := har.Save(); err != nil {
+		t.Fatalf("saving HAR file failed: %v", err)
+	}
+	content, _ := os.ReadFile(har.Path)
+	log := struct {
+		Log struct {
+			Version string     `json:"version"`
+			Entries []HarEntry `json:"entries"`
+		} `json:"log"`
+	}{}
+	if err := json.Unmarshal(content, &log); err != nil || log.Log.Version != "1.2" || len(log.Log.Entries) != 3 {
+		t.Errorf("unexpected HAR file: %s", content)
+	}
+}
+
+func TestRequestBuilder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/slow":
+			<-r.Context().Done()
+		case r.Method == http.MethodDelete:
+			w.Header().Set("X-Deleted", r.URL.Query().Get("force"))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			_, _ = fmt.Fprintf(w, `{"id":"%s","name":"%s"}`, r.Header.Get("Authorization"), r.Header.Get("X-Tenant"))
+		}
+	}))
+	defer server.Close()
+	tc := &testTokenContext{testContext: &testContext{url: server.URL}, token: "Bearer context"}
+	requester := New(WithReporter(tc, t), doc.CreateDocContext())
+	user := testUser{}
+	requester.Get("/users/{userId}").Params(struct {
+		UserId string `json:"userId"`
+	}{UserId: "1"}).Header("X-Tenant", "acme").Token("Bearer call").Into(&user).Do()
+	if user.Id != "Bearer call" || user.Name != "acme" {
+		t.Errorf("unexpected result: %+v", user)
+	}
+	response := requester.Delete("/users/1").Params(struct {
+		Force bool `json:"force"`
+	}{Force: true}).Expect(204).ExpectHeader("X-Deleted", "true").Do()
+	if response.StatusCode != 204 {
+		t.Errorf("unexpected response: %+v", response)
+	}
+	var observed error
+	dc := doc.CreateDocContext()
+	dc.NewEndpointDocumentation("", "users", "Delete user")
+	dc.CollectAll("Delete user")
+	c := WithObserver(tc, func(o Observation) { observed = o.Err })
+	_, err := New(c, dc).Delete("/users/1").Expect(204).ExpectHeader("X-Deleted", "true").TryDo()
+	if err == nil || !strings.Contains(err.Error(), "unexpected value of header X-Deleted") {
+		t.Errorf("expected failed assertion, got: %v", err)
+	}
+	if observed == nil || len(dc.GetEndpoint().Examples) != 0 {
+		t.Errorf("failed assertion reported as success, observed: %v, examples: %d", observed, len(dc.GetEndpoint().Examples))
+	}
+	_, err = requester.Get("/slow").Timeout(20 * time.Millisecond).TryDo()
+	var e *Error
+	if !errors.As(err, &e) || e.Timeout != 20*time.Millisecond {
+		t.Errorf("expected timed out request, got: %v", err)
+	}
+}
+
+func TestRequestTokenOverride(t *testing.T) {
+	issued := 0
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		issued++
+		_, _ = io.WriteString(w, `{"access_token":"good","token_type":"Bearer","expires_in":3600}`)
+	}))
+	defer tokenServer.Close()
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.Header.Get("Authorization") != "Bearer good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	tc := &testContext{url: server.URL}
+	New(WithReporter(tc, t), nil).Post("/orders").Body(testUser{Id: "1"}).Token("Bearer bad").Expect(401).Do()
+	if hits != 1 {
+		t.Errorf("request with token override sent %d times", hits)
+	}
+	c := WithTokenProvider(WithReporter(tc, t), ClientCredentials(tokenServer.URL, "app", "secret", ""))
+	hits = 0
+	New(c, nil).Post("/orders").Body(testUser{Id: "1"}).Token("Bearer bad").Expect(401).Do()
+	if hits != 1 || issued != 0 {
+		t.Errorf("token provider used for request with token override, hits: %d, issued: %d", hits, issued)
+	}
+	hits = 0
+	New(c, nil).Post("/orders").Body(testUser{Id: "1"}).Expect(201).Do()
+	if hits != 1 || issued != 1 {
+		t.Errorf("token provider not used, hits: %d, issued: %d", hits, issued)
+	}
+}
@@ -0,0 +1,80 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"sync"
+)
+
+// Function WithSession returns request context keeping cookies between requests.
+// All requests executed with the returned context (and contexts derived from it)
+// share the same cookie jar, so session cookies set by the server are sent back
+// in subsequent requests. The HTTP client of the specified context is copied
+// and only its cookie jar is replaced.
+func WithSession(c Context) Context {
+	jar, _ := cookiejar.New(nil) // never fails when options are nil
+	client := *clientOf(c)
+	client.Jar = jar
+	return WithClient(c, &client)
+}
+
+// Function WithCSRF returns request context protecting requests against CSRF checks.
+// The token is read from the cookie named cookieName or from the response header
+// named headerName, whichever was received most recently, and is sent back in the
+// header named headerName with every request using unsafe method (POST, PUT, PATCH, DELETE).
+// Use this function together with WithSession when the server keeps session in cookies.
+func WithCSRF(c Context, cookieName string, headerName string) Context {
+	t := &csrfToken{cookieName: cookieName, headerName: headerName}
+	return WithInterceptors(c, t.intercept)
+}
+
+// Type csrfToken holds the most recently received CSRF token.
+type csrfToken struct {
+	mutex      sync.Mutex // Guards the token shared by concurrent requests.
+	cookieName string     // Name of the cookie carrying the token.
+	headerName string     // Name of the header carrying the token.
+	value      string     // Most recently received token.
+}
+
+// Function intercept sends the token with unsafe requests and updates it from responses.
+func (t *csrfToken) intercept(req *http.Request, next Handler) (*http.Response, error) {
+	if unsafeMethod(req.Method) {
+		if value := t.get(); value != "" {
+			req.Header.Set(t.headerName, value)
+		}
+	}
+	res, err := next(req)
+	if err != nil {
+		return res, err
+	}
+	if value := res.Header.Get(t.headerName); value != "" {
+		t.set(value)
+	}
+	for _, cookie := range res.Cookies() {
+		if cookie.Name == t.cookieName && cookie.Value != "" {
+			t.set(cookie.Value)
+		}
+	}
+	return res, nil
+}
+
+func (t *csrfToken) get() string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.value
+}
+
+func (t *csrfToken) set(value string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.value = value
+}
+
+// Function unsafeMethod returns true for HTTP methods that modify the state of the server.
+func unsafeMethod(method string) bool {
+	switch method {
+	case httpPOST, httpPUT, httpPATCH, httpDELETE:
+		return true
+	}
+	return false
+}
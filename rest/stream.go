@@ -0,0 +1,287 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/wisbery/oxyde/common"
+	"github.com/wisbery/oxyde/doc"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	MediaTypeEventStream = "text/event-stream"    // Media type of Server-Sent Events stream.
+	MediaTypeNdjson      = "application/x-ndjson" // Media type of newline delimited JSON stream.
+	maxSampleEvents      = 10                     // Maximum number of events saved in documentation example.
+)
+
+// Type Event is a single event received from the stream.
+// For Server-Sent Events all fields are filled from the event fields,
+// for newline delimited streams (like NDJSON) only Data is filled with the content of a single line.
+type Event struct {
+	Id    string // Event identifier, the value of 'id' field.
+	Event string // Event type, the value of 'event' field.
+	Data  string // Event data, lines of multiline data are joined with new line character.
+}
+
+// Function Decode decodes JSON event data into v.
+func (e Event) Decode(v interface{}) error {
+	return json.Unmarshal([]byte(e.Data), v)
+}
+
+// Function String returns the event in the form it is sent in Server-Sent Events stream.
+func (e Event) String() string {
+	var b strings.Builder
+	if e.Id != "" {
+		b.WriteString("id: " + e.Id + "\n")
+	}
+	if e.Event != "" {
+		b.WriteString("event: " + e.Event + "\n")
+	}
+	for _, line := range strings.Split(e.Data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Type streamItem is an event or an error read from the stream.
+type streamItem struct {
+	event Event  // Received event.
+	raw   string // Event as received, used in documentation example.
+	err   error  // Error that ended the stream, io.EOF when the stream was closed by server.
+}
+
+// Type Stream is a streaming response, like Server-Sent Events or NDJSON,
+// read event by event as the events arrive.
+// Documentation data, with a sample of received events as the example,
+// is collected when the stream is closed.
+type Stream struct {
+	cl     *call                   // Request the stream is the response of.
+	cancel context.CancelCauseFunc // Cancels the request, closing the stream.
+	items  chan streamItem         // Events read from the stream.
+	done   chan struct{}           // Closed when the stream is closed by client.
+	wait   sync.WaitGroup          // Waits until reading the stream is finished.
+	sample []string                // Events saved in documentation example.
+	err    error                   // Error that ended the stream.
+	closed bool                    // Flag indicating if the stream was closed by client.
+}
+
+// Function HttpStream executes HTTP GET request and returns streaming response.
+// Returns nil when the request failed and the failure was reported.
+func HttpStream(c Context, dc *doc.Context, path string, params interface{}, status int) *Stream {
+	reporterOf(c).Helper()
+	s, err := TryHttpStream(c, dc, path, params, status)
+	failOnError(c, err)
+	return s
+}
+
+// Function TryHttpStream executes HTTP GET request and returns streaming response.
+// The response body is not read until the events are requested using Next or Expect,
+// the timeout of request context limits only the time of receiving response headers.
+// The request accepts Server-Sent Events and NDJSON streams, replacing the 'Accept' header of request context.
+// Returns *Error when the request failed or the returned status code differs from the expected one.
+func TryHttpStream(c Context, dc *doc.Context, path string, params interface{}, status int) (*Stream, error) {
+	cl := newCall(c, dc, httpGET, path, params, nil, nil, status)
+	s, err := cl.open()
+	cl.finish(err)
+	return s, err
+}
+
+// Function open sends the request and returns the stream of events
+// when the response has expected status code.
+func (cl *call) open() (*Stream, error) {
+	if err := cl.prepare(); err != nil {
+		return nil, err
+	}
+	cl.header = http.Header{"Accept": {MediaTypeEventStream + ", " + MediaTypeNdjson}}
+	cancel, err := cl.start()
+	if err != nil {
+		return nil, err
+	}
+	s := &Stream{
+		cl:     cl,
+		cancel: cancel,
+		items:  make(chan streamItem),
+		done:   make(chan struct{})}
+	s.wait.Add(1)
+	go s.read(isEventStream(cl.res.Header.Get("Content-Type")))
+	return s, nil
+}
+
+// Function isEventStream returns true when specified content type is Server-Sent Events stream.
+func isEventStream(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == MediaTypeEventStream
+}
+
+// Function isStreaming returns true when specified content type is a stream read as the events arrive.
+func isStreaming(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == MediaTypeEventStream || mediaType == MediaTypeNdjson)
+}
+
+// Function read reads events from the response body until the stream ends or is closed by client.
+func (s *Stream) read(eventStream bool) {
+	defer s.wait.Done()
+	defer func() { _ = s.cl.res.Body.Close() }()
+	reader := bufio.NewReader(s.cl.res.Body)
+	var event Event
+	var raw, data []string
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if err != nil && line == "" {
+			if !s.emit(streamItem{err: err}) {
+				return
+			}
+			close(s.items)
+			return
+		}
+		if !eventStream {
+			if strings.TrimSpace(line) != "" && !s.emit(streamItem{event: Event{Data: line}, raw: line}) {
+				return
+			}
+			continue
+		}
+		if line == "" {
+			if len(raw) > 0 && (data != nil || event.Event != "" || event.Id != "") {
+				event.Data = strings.Join(data, "\n")
+				if !s.emit(streamItem{event: event, raw: strings.Join(raw, "\n")}) {
+					return
+				}
+			}
+			event, raw, data = Event{}, nil, nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		raw = append(raw, line)
+		name, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch name {
+		case "id":
+			event.Id = value
+		case "event":
+			event.Event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+}
+
+// Function emit passes the item to the client, returns false when the stream was closed by client.
+func (s *Stream) emit(item streamItem) bool {
+	select {
+	case s.items <- item:
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+// Function Next returns the next event received from the stream.
+// Reports failure when no event was received within specified timeout or the stream ended.
+func (s *Stream) Next(timeout time.Duration) Event {
+	reporterOf(s.cl.c).Helper()
+	event, err := s.TryNext(timeout)
+	failOnError(s.cl.c, err)
+	return event
+}
+
+// Function TryNext returns the next event received from the stream.
+// Returns *Error when no event was received within specified timeout or the stream ended,
+// the cause of the error is io.EOF when the stream was closed by server.
+func (s *Stream) TryNext(timeout time.Duration) (Event, error) {
+	if s.err != nil {
+		return Event{}, s.cl.error(s.err)
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case item, ok := <-s.items:
+		if !ok {
+			s.err = io.EOF
+			return Event{}, s.cl.error(s.err)
+		}
+		if item.err != nil {
+			s.err = item.err
+			return Event{}, s.cl.error(s.err)
+		}
+		if s.cl.c.GetVerbose() {
+			fmt.Printf("\n<=== EVENT:\n%s\n", item.raw)
+		}
+		if len(s.sample) < maxSampleEvents {
+			s.sample = append(s.sample, item.raw)
+		}
+		return item.event, nil
+	case <-timer.C:
+		return Event{}, s.cl.error(fmt.Errorf("no event received within %v", timeout))
+	}
+}
+
+// Function Expect verifies that the next events received from the stream match the expected events,
+// each event has to be received within specified timeout.
+// Only non-empty fields of expected events are compared. Reports failure when events do not match.
+func (s *Stream) Expect(timeout time.Duration, expected ...Event) {
+	r := reporterOf(s.cl.c)
+	r.Helper()
+	for i, e := range expected {
+		actual, err := s.TryNext(timeout)
+		if err != nil {
+			failOnError(s.cl.c, err)
+			return
+		}
+		if !matchesEvent(e, actual) {
+			common.Fail(r, fmt.Sprintf("unexpected event %d", i+1), e, actual)
+			return
+		}
+	}
+}
+
+// Function TryExpect verifies that the next events received from the stream match the expected events,
+// each event has to be received within specified timeout.
+// Only non-empty fields of expected events are compared. Returns an error when events do not match.
+func (s *Stream) TryExpect(timeout time.Duration, expected ...Event) error {
+	for i, e := range expected {
+		actual, err := s.TryNext(timeout)
+		if err != nil {
+			return err
+		}
+		if !matchesEvent(e, actual) {
+			return s.cl.error(fmt.Errorf("unexpected event %d, expected: %+v, actual: %+v", i+1, e, actual))
+		}
+	}
+	return nil
+}
+
+// Function matchesEvent returns true when non-empty fields of expected event are equal to actual event fields.
+func matchesEvent(expected Event, actual Event) bool {
+	return (expected.Id == "" || expected.Id == actual.Id) &&
+		(expected.Event == "" || expected.Event == actual.Event) &&
+		(expected.Data == "" || expected.Data == actual.Data)
+}
+
+// Function Close closes the stream and collects documentation data
+// with the events received so far as the example response body.
+func (s *Stream) Close() {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.done)
+	s.cancel(nil)
+	s.wait.Wait()
+	separator := "\n"
+	if isEventStream(s.cl.res.Header.Get("Content-Type")) {
+		separator = "\n\n"
+	}
+	s.cl.responseBody = []byte(strings.Join(s.sample, separator))
+	collectDocumentationData(s.cl)
+}
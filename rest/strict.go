@@ -0,0 +1,166 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/wisbery/oxyde/common"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Type StrictMode defines how response properties not declared in the result are handled.
+type StrictMode int32
+
+const (
+	StrictOff  StrictMode = iota // Unknown properties are silently ignored, like json.Unmarshal does.
+	StrictWarn                   // Unknown properties are reported as a warning, the request does not fail.
+	StrictFail                   // Unknown properties fail the request.
+)
+
+var defaultStrictMode int32 // Strict mode used when request context does not define its own mode.
+
+// Type UnknownFieldsError describes response properties not declared in the result.
+type UnknownFieldsError struct {
+	Paths []string // Full JSON paths of unknown properties, like $.user.address.zip.
+}
+
+// Function Error returns the text describing unknown properties.
+func (e *UnknownFieldsError) Error() string {
+	return "unknown fields in response: " + strings.Join(e.Paths, ", ")
+}
+
+// Function SetStrictDecoding sets the strict mode used by request contexts
+// that do not define their own mode (see WithStrictDecoding).
+func SetStrictDecoding(mode StrictMode) {
+	atomic.StoreInt32(&defaultStrictMode, int32(mode))
+}
+
+// Function WithStrictDecoding returns request context decoding JSON responses in specified strict mode.
+// In modes other than StrictOff the response properties that are not declared
+// in the result are reported with their full JSON paths.
+func WithStrictDecoding(c Context, mode StrictMode) Context {
+	e := extend(c)
+	e.strictMode = &mode
+	return e
+}
+
+// Function strictModeOf returns the strict mode for specified request context.
+func strictModeOf(c Context) StrictMode {
+	if e, ok := c.(*extendedContext); ok && e.strictMode != nil {
+		return *e.strictMode
+	}
+	return StrictMode(atomic.LoadInt32(&defaultStrictMode))
+}
+
+// Function checkUnknownFields reports properties of JSON response body
+// that are not declared in the result, according to the strict mode of the request context.
+func checkUnknownFields(c Context, method string, uri string, body []byte, result interface{}) error {
+	mode := strictModeOf(c)
+	if mode == StrictOff {
+		return nil
+	}
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil
+	}
+	paths := unknownFields(data, reflect.TypeOf(result), "$")
+	if len(paths) == 0 {
+		return nil
+	}
+	err := &UnknownFieldsError{Paths: paths}
+	if mode == StrictWarn {
+		fmt.Printf("\nWARNING: %s %s: %v\n", method, uri, err)
+		return nil
+	}
+	return err
+}
+
+// Function unknownFields returns JSON paths of properties in decoded data
+// that have no corresponding fields in specified type.
+func unknownFields(data interface{}, typ reflect.Type, path string) []string {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	paths := make([]string, 0)
+	switch value := data.(type) {
+	case map[string]interface{}:
+		if typ.Kind() == reflect.Map {
+			for _, key := range sortedKeys(value) {
+				paths = append(paths, unknownFields(value[key], typ.Elem(), path+"."+key)...)
+			}
+			return paths
+		}
+		if typ.Kind() != reflect.Struct {
+			return paths
+		}
+		fields := jsonFields(typ)
+		for _, key := range sortedKeys(value) {
+			field, ok := fields[key]
+			if !ok {
+				field, ok = fields[strings.ToLower(key)]
+			}
+			if !ok {
+				paths = append(paths, path+"."+key)
+				continue
+			}
+			paths = append(paths, unknownFields(value[key], field, path+"."+key)...)
+		}
+	case []interface{}:
+		if typ.Kind() != reflect.Slice && typ.Kind() != reflect.Array {
+			return paths
+		}
+		for i, item := range value {
+			paths = append(paths, unknownFields(item, typ.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+	return paths
+}
+
+// Function jsonFields returns the types of struct fields by their JSON names.
+// Fields of embedded structs are included, like in encoding/json.
+// Lower-cased names are added as well, as JSON keys are matched case-insensitively.
+func jsonFields(typ reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name := common.TagName(field, common.JsonTagName)
+		if name == "-" {
+			continue
+		}
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			for embeddedName, embeddedType := range jsonFields(fieldType) {
+				if _, ok := fields[embeddedName]; !ok {
+					fields[embeddedName] = embeddedType
+				}
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+		if _, ok := fields[strings.ToLower(name)]; !ok {
+			fields[strings.ToLower(name)] = field.Type
+		}
+	}
+	return fields
+}
+
+// Function sortedKeys returns the keys of JSON object in alphabetical order.
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
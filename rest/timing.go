@@ -0,0 +1,118 @@
+package rest
+
+import (
+	"context"
+	"crypto/tls"
+	"github.com/wisbery/oxyde/doc"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Function WithMaxDuration returns request context failing every request that takes longer
+// than specified duration, the same way as requests returning unexpected status code.
+func WithMaxDuration(c Context, maxDuration time.Duration) Context {
+	e := extend(c)
+	e.maxDuration = maxDuration
+	return e
+}
+
+// Function WithEndpointMaxDuration returns request context failing requests to specified endpoint
+// that take longer than specified duration. The endpoint is identified by HTTP method
+// and request path with placeholders for parameters, like /users/{userId}.
+// The limit defined for the endpoint takes precedence over the limit set by WithMaxDuration.
+func WithEndpointMaxDuration(c Context, method string, path string, maxDuration time.Duration) Context {
+	e := extend(c)
+	limits := make(map[string]time.Duration)
+	for key, value := range e.endpointMaxDurations {
+		limits[key] = value
+	}
+	limits[method+" "+path] = maxDuration
+	e.endpointMaxDurations = limits
+	return e
+}
+
+// Function maxDurationOf returns the time limit for the request to specified endpoint, zero when not limited.
+func maxDurationOf(c Context, method string, path string) time.Duration {
+	if e, ok := c.(*extendedContext); ok {
+		if maxDuration, ok := e.endpointMaxDurations[method+" "+path]; ok {
+			return maxDuration
+		}
+		return e.maxDuration
+	}
+	return 0
+}
+
+// Type tracer measures phases of HTTP request using httptrace.
+type tracer struct {
+	mutex        sync.Mutex // Guards measured times, trace hooks may be called from different goroutines.
+	start        time.Time  // Time when the request was started.
+	dnsStart     time.Time  // Time when the DNS lookup was started.
+	connectStart time.Time  // Time when the first connection attempt was started.
+	tlsStart     time.Time  // Time when TLS handshake was started.
+	timing       doc.Timing // Measured durations.
+}
+
+// Function withTracer returns the context measuring phases of requests bound to it.
+func withTracer(ctx context.Context, t *tracer) context.Context {
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			t.timing.DNS = time.Since(t.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(_ string, _ string, err error) {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			if err == nil {
+				t.timing.Connect = time.Since(t.connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			t.timing.TLS = time.Since(t.tlsStart)
+		},
+		GotFirstResponseByte: func() {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			t.timing.TTFB = time.Since(t.start)
+		},
+	}
+	return httptrace.WithClientTrace(context.WithValue(ctx, tracerKey{}, t), trace)
+}
+
+// Type tracerKey is the key of the tracer stored in context.
+type tracerKey struct{}
+
+// Function tracerFrom returns the tracer measuring phases of requests bound to specified context, nil if none.
+func tracerFrom(ctx context.Context) *tracer {
+	t, _ := ctx.Value(tracerKey{}).(*tracer)
+	return t
+}
+
+// Function result returns measured durations with specified total duration of the request.
+func (t *tracer) result(total time.Duration) doc.Timing {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	timing := t.timing
+	timing.Total = total
+	return timing
+}
@@ -0,0 +1,403 @@
+package rest
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/wisbery/oxyde/common"
+	"github.com/wisbery/oxyde/doc"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	MethodWS           = "WS"                                   // Name of the endpoint kind used to document WebSocket channels.
+	websocketGuid      = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11" // Value defined in RFC 6455 for computing 'Sec-WebSocket-Accept' header.
+	opContinuation     = 0x0                                    // Opcode of continuation frame.
+	opText             = 0x1                                    // Opcode of text frame.
+	opBinary           = 0x2                                    // Opcode of binary frame.
+	opClose            = 0x8                                    // Opcode of connection close frame.
+	opPing             = 0x9                                    // Opcode of ping frame.
+	opPong             = 0xA                                    // Opcode of pong frame.
+	closeNormal        = 1000                                   // Status code of normal connection closure.
+	closeHandshakeTime = time.Second                            // Time the server has to answer closing the connection.
+	maxSampleMessages  = 10                                     // Maximum number of messages in each direction saved in documentation example.
+	maxFrameSize       = 16 << 20                               // Maximum size of received frame payload in bytes.
+)
+
+// Type wsMessage is a message or an error read from WebSocket connection.
+type wsMessage struct {
+	data []byte // Message content.
+	err  error  // Error that ended the connection, io.EOF when the connection was closed by server.
+}
+
+// Type WebSocket is the client side of WebSocket connection exchanging JSON messages.
+// The connection is opened using URL, authorization token, headers and HTTP client of request context.
+// Documentation data, with exchanged messages as the example, is collected when the connection is closed.
+type WebSocket struct {
+	cl       *call                   // Opening handshake request.
+	cancel   context.CancelCauseFunc // Cancels the opening handshake request, closing the connection.
+	conn     io.ReadWriteCloser      // Upgraded connection.
+	mutex    sync.Mutex              // Guards writing frames, pong frames are written while reading.
+	messages chan wsMessage          // Messages read from the connection.
+	closing  chan struct{}           // Closed when the client starts closing the connection.
+	wait     sync.WaitGroup          // Waits until reading the connection is finished.
+	sent     []string                // Sent messages saved in documentation example.
+	received []string                // Received messages saved in documentation example.
+	sentType interface{}             // First sent message, documented as the request body.
+	recvType interface{}             // First message the received message was decoded into, documented as the response body.
+	err      error                   // Error that ended the connection.
+	closed   bool                    // Flag indicating if the connection was closed by client.
+}
+
+// Function WsOpen opens WebSocket connection to specified path.
+// Returns nil when opening the connection failed and the failure was reported.
+func WsOpen(c Context, dc *doc.Context, path string, params interface{}) *WebSocket {
+	reporterOf(c).Helper()
+	ws, err := TryWsOpen(c, dc, path, params)
+	failOnError(c, err)
+	return ws
+}
+
+// Function TryWsOpen opens WebSocket connection to specified path.
+// URL of request context with scheme http or https is used to connect using ws or wss protocol respectively.
+// Returns *Error when the opening handshake failed.
+func TryWsOpen(c Context, dc *doc.Context, path string, params interface{}) (*WebSocket, error) {
+	cl := newCall(c, dc, httpGET, path, params, nil, nil, http.StatusSwitchingProtocols)
+	ws, err := cl.upgrade()
+	cl.finish(err)
+	return ws, err
+}
+
+// Function upgrade executes the opening handshake and returns WebSocket connection.
+func (cl *call) upgrade() (*WebSocket, error) {
+	if err := cl.prepare(); err != nil {
+		return nil, err
+	}
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return nil, cl.error(err)
+	}
+	encodedKey := base64.StdEncoding.EncodeToString(key)
+	cl.header = http.Header{
+		"Connection":            {"Upgrade"},
+		"Upgrade":               {"websocket"},
+		"Sec-Websocket-Version": {"13"},
+		"Sec-Websocket-Key":     {encodedKey}}
+	cancel, err := cl.start()
+	if err != nil {
+		return nil, err
+	}
+	conn, ok := cl.res.Body.(io.ReadWriteCloser)
+	if !ok || !strings.EqualFold(cl.res.Header.Get("Upgrade"), "websocket") || cl.res.Header.Get("Sec-Websocket-Accept") != acceptKey(encodedKey) {
+		_ = cl.res.Body.Close()
+		cancel(nil)
+		return nil, cl.error(errors.New("invalid WebSocket opening handshake response"))
+	}
+	cl.method = MethodWS
+	cl.uri = strings.Replace(cl.uri, "http", "ws", 1)
+	ws := &WebSocket{
+		cl:       cl,
+		cancel:   cancel,
+		conn:     conn,
+		messages: make(chan wsMessage),
+		closing:  make(chan struct{})}
+	ws.wait.Add(1)
+	go ws.read()
+	return ws, nil
+}
+
+// Function acceptKey returns the value of 'Sec-WebSocket-Accept' header expected for specified key.
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGuid))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Function writeFrame writes single masked frame with specified opcode and payload.
+func (ws *WebSocket) writeFrame(opcode byte, payload []byte) error {
+	frame := []byte{0x80 | opcode}
+	switch length := len(payload); {
+	case length < 126:
+		frame = append(frame, 0x80|byte(length))
+	case length <= 0xFFFF:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+	mask := make([]byte, 4)
+	if _, err := rand.Read(mask); err != nil {
+		return err
+	}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	_, err := ws.conn.Write(frame)
+	return err
+}
+
+// Function readFrame reads single frame, returns its FIN flag, opcode and unmasked payload.
+// Returns an error when the payload is larger than maxFrameSize.
+func readFrame(reader *bufio.Reader) (bool, byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return false, 0, nil, err
+	}
+	fin := header[0]&0x80 != 0
+	opcode := header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+		if _, err := io.ReadFull(reader, extended); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+		if _, err := io.ReadFull(reader, extended); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended)
+	}
+	if length > maxFrameSize {
+		return false, 0, nil, fmt.Errorf("frame payload of %d bytes exceeds the limit of %d bytes", length, maxFrameSize)
+	}
+	mask := make([]byte, 4)
+	if masked {
+		if _, err := io.ReadFull(reader, mask); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
+
+// Function read reads messages from the connection, answers ping frames
+// and finishes when the connection is closed.
+func (ws *WebSocket) read() {
+	defer ws.wait.Done()
+	defer close(ws.messages)
+	reader := bufio.NewReader(ws.conn)
+	var message []byte
+	for {
+		fin, opcode, payload, err := readFrame(reader)
+		if err != nil {
+			ws.emit(wsMessage{err: err})
+			return
+		}
+		switch opcode {
+		case opPing:
+			_ = ws.writeFrame(opPong, payload)
+		case opPong:
+		case opClose:
+			select {
+			case <-ws.closing:
+			default:
+				_ = ws.writeFrame(opClose, payload)
+			}
+			ws.emit(wsMessage{err: io.EOF})
+			return
+		case opText, opBinary, opContinuation:
+			message = append(message, payload...)
+			if fin {
+				if !ws.emit(wsMessage{data: message}) {
+					return
+				}
+				message = nil
+			}
+		}
+	}
+}
+
+// Function emit passes the message to the client, returns false when the client closed the connection.
+func (ws *WebSocket) emit(message wsMessage) bool {
+	select {
+	case ws.messages <- message:
+		return true
+	case <-ws.closing:
+		return false
+	}
+}
+
+// Function Send sends the value encoded as JSON text message.
+func (ws *WebSocket) Send(v interface{}) {
+	reporterOf(ws.cl.c).Helper()
+	failOnError(ws.cl.c, ws.TrySend(v))
+}
+
+// Function TrySend sends the value encoded as JSON text message.
+func (ws *WebSocket) TrySend(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ws.cl.error(err)
+	}
+	if ws.cl.c.GetVerbose() {
+		fmt.Printf("\n===> MESSAGE:\n%s\n", common.PrettyPrint(data))
+	}
+	if err = ws.writeFrame(opText, data); err != nil {
+		return ws.cl.error(err)
+	}
+	if ws.sentType == nil {
+		ws.sentType = v
+	}
+	if len(ws.sent) < maxSampleMessages {
+		ws.sent = append(ws.sent, common.PrettyPrint(data))
+	}
+	return nil
+}
+
+// Function Receive decodes the next JSON message received within specified timeout into v.
+// Reports failure when no message was received within the timeout or the connection was closed.
+func (ws *WebSocket) Receive(timeout time.Duration, v interface{}) {
+	reporterOf(ws.cl.c).Helper()
+	failOnError(ws.cl.c, ws.TryReceive(timeout, v))
+}
+
+// Function TryReceive decodes the next JSON message received within specified timeout into v.
+// Returns *Error when no message was received within the timeout or the connection was closed,
+// the cause of the error is io.EOF when the connection was closed by server.
+func (ws *WebSocket) TryReceive(timeout time.Duration, v interface{}) error {
+	data, err := ws.next(timeout)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return ws.cl.error(err)
+	}
+	if ws.recvType == nil {
+		ws.recvType = v
+	}
+	return nil
+}
+
+// Function Expect verifies that the next JSON message received within specified timeout
+// is equal to the expected value encoded as JSON. Reports failure when messages differ.
+func (ws *WebSocket) Expect(timeout time.Duration, expected interface{}) {
+	r := reporterOf(ws.cl.c)
+	r.Helper()
+	actual, err := ws.expect(timeout, expected)
+	if err != nil {
+		failOnError(ws.cl.c, err)
+		return
+	}
+	if actual != nil {
+		common.Fail(r, "unexpected message", expected, actual)
+	}
+}
+
+// Function TryExpect verifies that the next JSON message received within specified timeout
+// is equal to the expected value encoded as JSON. Returns an error when messages differ.
+func (ws *WebSocket) TryExpect(timeout time.Duration, expected interface{}) error {
+	actual, err := ws.expect(timeout, expected)
+	if err != nil {
+		return err
+	}
+	if actual != nil {
+		return ws.cl.error(fmt.Errorf("unexpected message, expected: %+v, actual: %+v", expected, actual))
+	}
+	return nil
+}
+
+// Function expect receives the next message and compares it with the expected value,
+// returns the received message when it differs from the expected one, nil otherwise.
+func (ws *WebSocket) expect(timeout time.Duration, expected interface{}) (interface{}, error) {
+	data, err := ws.next(timeout)
+	if err != nil {
+		return nil, err
+	}
+	expectedData, err := json.Marshal(expected)
+	if err != nil {
+		return nil, ws.cl.error(err)
+	}
+	var e, a interface{}
+	if err = json.Unmarshal(expectedData, &e); err != nil {
+		return nil, ws.cl.error(err)
+	}
+	if err = json.Unmarshal(data, &a); err != nil {
+		return nil, ws.cl.error(err)
+	}
+	if ws.recvType == nil {
+		ws.recvType = expected
+	}
+	if !reflect.DeepEqual(e, a) {
+		return string(data), nil
+	}
+	return nil, nil
+}
+
+// Function next returns the next message received within specified timeout.
+func (ws *WebSocket) next(timeout time.Duration) ([]byte, error) {
+	if ws.err != nil {
+		return nil, ws.cl.error(ws.err)
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case message, ok := <-ws.messages:
+		if !ok {
+			ws.err = io.EOF
+			return nil, ws.cl.error(ws.err)
+		}
+		if message.err != nil {
+			ws.err = message.err
+			return nil, ws.cl.error(ws.err)
+		}
+		if ws.cl.c.GetVerbose() {
+			fmt.Printf("\n<=== MESSAGE:\n%s\n", common.PrettyPrint(message.data))
+		}
+		if len(ws.received) < maxSampleMessages {
+			ws.received = append(ws.received, common.PrettyPrint(message.data))
+		}
+		return message.data, nil
+	case <-timer.C:
+		return nil, ws.cl.error(fmt.Errorf("no message received within %v", timeout))
+	}
+}
+
+// Function Close closes the connection and collects documentation data
+// with exchanged messages as the example request and response bodies.
+func (ws *WebSocket) Close() {
+	if ws.closed {
+		return
+	}
+	ws.closed = true
+	close(ws.closing)
+	if ws.err == nil {
+		_ = ws.writeFrame(opClose, binary.BigEndian.AppendUint16(nil, closeNormal))
+		timer := time.AfterFunc(closeHandshakeTime, func() { _ = ws.conn.Close() })
+		ws.wait.Wait()
+		timer.Stop()
+	}
+	_ = ws.conn.Close()
+	ws.cancel(nil)
+	ws.wait.Wait()
+	ws.cl.payload = ws.sentType
+	ws.cl.result = ws.recvType
+	ws.cl.requestText = strings.Join(ws.sent, "\n\n")
+	ws.cl.responseBody = []byte(strings.Join(ws.received, "\n\n"))
+	collectDocumentationData(ws.cl)
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"fmt"
+	d "github.com/wisbery/oxyde/doc"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	MockStatusHeader  = "X-Mock-Status"  // Request header selecting the example by status code.
+	MockExampleHeader = "X-Mock-Example" // Request header selecting the example by summary.
+)
+
+// Response headers that are not copied from examples, because they describe the original transfer.
+var skippedMockHeaders = map[string]bool{"Content-Length": true, "Date": true, "Transfer-Encoding": true, "Connection": true}
+
+// Regular expression matching placeholders in URL path templates, like {userId}.
+var rePlaceholder = regexp.MustCompile(`\{[^/{}]+\}`)
+
+// Function StartMock starts mock server answering requests with documented examples.
+func StartMock(dc *d.Context) {
+	runMockServer(dc, 16200)
+}
+
+func runMockServer(dc *d.Context, port int) {
+	fmt.Printf(">> API mock server started and listening on port: %d\n", port)
+	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", port), MockHandler(dc)))
+}
+
+// Type mockEndpoint is a documented endpoint prepared for matching requests.
+type mockEndpoint struct {
+	method   string         // HTTP method name in uppercase.
+	path     *regexp.Regexp // Regular expression matching URL path template.
+	root     string         // URL path of the request URL root, removed from URIs of examples.
+	examples []d.Example    // Documented examples.
+}
+
+// Function MockHandler returns HTTP handler answering requests with examples collected in documentation context.
+// Requests are matched by HTTP method and URL path template, where placeholders like {userId} match any path segment.
+// The example is selected by status code passed in X-Mock-Status header or by summary passed in X-Mock-Example header.
+// Without these headers the first successful example is selected, preferring examples with the same URL path.
+func MockHandler(dc *d.Context) http.Handler {
+	endpoints := make([]mockEndpoint, 0)
+	for _, endpoint := range dc.GetEndpoints() {
+		if len(endpoint.Examples) == 0 {
+			continue
+		}
+		root := ""
+		if rootUrl, err := url.Parse(endpoint.UrlRoot); err == nil {
+			root = strings.TrimSuffix(rootUrl.Path, "/")
+		}
+		endpoints = append(endpoints, mockEndpoint{
+			method:   strings.ToUpper(endpoint.Method),
+			path:     compilePathTemplate(endpoint.UrlPath),
+			root:     root,
+			examples: endpoint.Examples})
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		found := false
+		for _, endpoint := range endpoints {
+			if endpoint.method != req.Method || !endpoint.path.MatchString(req.URL.Path) {
+				continue
+			}
+			found = true
+			if example := selectExample(endpoint.examples, endpoint.root, req); example != nil {
+				writeExample(w, example)
+				return
+			}
+		}
+		if found {
+			writeMockError(w, http.StatusNotFound, "no documented example matches the request")
+		} else {
+			writeMockError(w, http.StatusNotFound, "no documented endpoint matches the request")
+		}
+	})
+}
+
+// Function compilePathTemplate returns regular expression matching specified URL path template.
+func compilePathTemplate(template string) *regexp.Regexp {
+	pattern := ""
+	last := 0
+	for _, loc := range rePlaceholder.FindAllStringIndex(template, -1) {
+		pattern += regexp.QuoteMeta(template[last:loc[0]]) + `[^/]*`
+		last = loc[1]
+	}
+	pattern += regexp.QuoteMeta(template[last:])
+	return regexp.MustCompile("^" + pattern + "/?$")
+}
+
+// Function selectExample returns the example answering specified request or nil when there is no such example.
+// Paths of example URIs are compared with the request path after removing the path of the URL root.
+func selectExample(examples []d.Example, root string, req *http.Request) *d.Example {
+	candidates := make([]*d.Example, 0)
+	status, statusErr := strconv.Atoi(req.Header.Get(MockStatusHeader))
+	summary := req.Header.Get(MockExampleHeader)
+	for i := range examples {
+		example := &examples[i]
+		if statusErr == nil && example.StatusCode != status {
+			continue
+		}
+		if summary != "" && example.Summary != summary {
+			continue
+		}
+		if statusErr != nil && summary == "" && (example.StatusCode < 200 || example.StatusCode > 299) {
+			continue
+		}
+		candidates = append(candidates, example)
+	}
+	for _, example := range candidates {
+		if exampleUrl, err := url.Parse(example.Uri); err == nil && strings.TrimPrefix(exampleUrl.Path, root) == req.URL.Path {
+			return example
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates[0]
+	}
+	return nil
+}
+
+// Function writeExample writes the documented response of the example.
+func writeExample(w http.ResponseWriter, example *d.Example) {
+	for name, values := range example.ResponseHeaders {
+		if skippedMockHeaders[http.CanonicalHeaderKey(name)] {
+			continue
+		}
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	if w.Header().Get("Content-Type") == "" && example.ResponseBody != "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(example.StatusCode)
+	_, _ = io.WriteString(w, example.ResponseBody)
+}
+
+// Function writeMockError writes JSON error response of the mock server.
+func writeMockError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, `{"error":%q}`, message)
+}
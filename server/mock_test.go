@@ -0,0 +1,91 @@
+package server
+
+import (
+	d "github.com/wisbery/oxyde/doc"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func createMockDocContext() *d.Context {
+	dc := d.CreateDocContext()
+	dc.NewEndpointDocumentation("", "users", "Find user")
+	endpoint := dc.GetEndpoint()
+	endpoint.Method = "GET"
+	endpoint.UrlPath = "/users/{userId}"
+	endpoint.Examples = []d.Example{
+		{Summary: "Not found", StatusCode: 404, Uri: "http://api.test/users/0", ResponseBody: `{"error":"not found"}`},
+		{Summary: "Found", StatusCode: 200, Uri: "http://api.test/users/1", ResponseBody: `{"id":"1"}`,
+			ResponseHeaders: http.Header{"Content-Type": {"application/json"}, "X-Request-Id": {"r-1"}}},
+		{Summary: "Found other", StatusCode: 200, Uri: "http://api.test/users/2", ResponseBody: `{"id":"2"}`}}
+	dc.SaveEndpointDocumentation()
+	return dc
+}
+
+func mockRequest(t *testing.T, handler http.Handler, method string, path string, header map[string]string) (*http.Response, string) {
+	req := httptest.NewRequest(method, path, nil)
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	res := w.Result()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res, string(body)
+}
+
+func TestMockHandlerSelectsExample(t *testing.T) {
+	handler := MockHandler(createMockDocContext())
+	res, body := mockRequest(t, handler, "GET", "/users/7", nil)
+	if res.StatusCode != 200 || body != `{"id":"1"}` || res.Header.Get("X-Request-Id") != "r-1" {
+		t.Errorf("unexpected default example: %d %s", res.StatusCode, body)
+	}
+	res, body = mockRequest(t, handler, "GET", "/users/2", nil)
+	if res.StatusCode != 200 || body != `{"id":"2"}` {
+		t.Errorf("unexpected example for matching path: %d %s", res.StatusCode, body)
+	}
+	res, body = mockRequest(t, handler, "GET", "/users/7", map[string]string{MockStatusHeader: "404"})
+	if res.StatusCode != 404 || body != `{"error":"not found"}` {
+		t.Errorf("unexpected example selected by status: %d %s", res.StatusCode, body)
+	}
+	res, body = mockRequest(t, handler, "GET", "/users/7", map[string]string{MockExampleHeader: "Found other"})
+	if res.StatusCode != 200 || body != `{"id":"2"}` {
+		t.Errorf("unexpected example selected by summary: %d %s", res.StatusCode, body)
+	}
+}
+
+func TestMockHandlerNoMatch(t *testing.T) {
+	handler := MockHandler(createMockDocContext())
+	for _, path := range []string{"/users", "/users/1/roles", "/accounts/1"} {
+		if res, _ := mockRequest(t, handler, "GET", path, nil); res.StatusCode != 404 {
+			t.Errorf("expected no match for path %s, got: %d", path, res.StatusCode)
+		}
+	}
+	if res, _ := mockRequest(t, handler, "DELETE", "/users/1", nil); res.StatusCode != 404 {
+		t.Errorf("expected no match for method, got: %d", res.StatusCode)
+	}
+	if res, _ := mockRequest(t, handler, "GET", "/users/1", map[string]string{MockStatusHeader: "500"}); res.StatusCode != 404 {
+		t.Errorf("expected no matching example, got: %d", res.StatusCode)
+	}
+}
+
+func TestMockHandlerUrlRootPath(t *testing.T) {
+	dc := d.CreateDocContext()
+	dc.NewEndpointDocumentation("", "users", "Find user")
+	endpoint := dc.GetEndpoint()
+	endpoint.Method = "GET"
+	endpoint.UrlRoot = "http://api.test/v1/"
+	endpoint.UrlPath = "/users/{userId}"
+	endpoint.Examples = []d.Example{
+		{Summary: "Found", StatusCode: 200, Uri: "http://api.test/v1/users/1", ResponseBody: `{"id":"1"}`},
+		{Summary: "Found other", StatusCode: 200, Uri: "http://api.test/v1/users/2", ResponseBody: `{"id":"2"}`}}
+	dc.SaveEndpointDocumentation()
+	res, body := mockRequest(t, MockHandler(dc), "GET", "/users/2", nil)
+	if res.StatusCode != 200 || body != `{"id":"2"}` {
+		t.Errorf("unexpected example for matching path: %d %s", res.StatusCode, body)
+	}
+}
@@ -18,6 +18,7 @@ var (
 	indexTemplate    = prepareIndexTemplate()
 	endpointTemplate = prepareEndpointTemplate()
 	errorTemplate    = prepareErrorTemplate()
+	loadTemplate     = prepareLoadReportTemplate()
 )
 
 func StartPreview(dc *d.Context) {
@@ -53,9 +54,15 @@ func runPreviewServer(model *m.Model, port int) {
 		return
 	}
 
+	loadReports := func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		wrapInPage(w, loadTemplate, model)
+	}
+
 	http.HandleFunc("/", index)
 	http.HandleFunc("/style.css", styleCss)
 	http.HandleFunc("/endpoint-details", endpointDetails)
+	http.HandleFunc("/load-reports", loadReports)
 	fmt.Printf(">> API preview server started and listening on port: %d\n", port)
 	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", port), nil))
 }
@@ -84,6 +91,12 @@ func prepareErrorTemplate() *template.Template {
 	return t
 }
 
+func prepareLoadReportTemplate() *template.Template {
+	t, err := template.New("loadReportTemplate").Parse(h.LoadReportTemplate)
+	common.PanicOnError(err)
+	return t
+}
+
 func wrapInPage(w http.ResponseWriter, t *template.Template, data interface{}) {
 	var out bytes.Buffer
 	outWriter := io.Writer(&out)
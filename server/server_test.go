@@ -0,0 +1,47 @@
+package server
+
+import (
+	"bytes"
+	d "github.com/wisbery/oxyde/doc"
+	m "github.com/wisbery/oxyde/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadReportTemplate(t *testing.T) {
+	dc := d.CreateDocContext()
+	dc.SaveLoadReport(d.LoadReport{
+		Name:        "Users",
+		Concurrency: 4,
+		Duration:    time.Second,
+		Iterations:  100,
+		Endpoints: []d.LoadStats{
+			{Method: "GET", UrlPath: "/users/{userId}", Requests: 100, Errors: 1, ErrorRate: 0.01, Throughput: 100, P50: 1500 * time.Microsecond}}})
+	var out bytes.Buffer
+	if err := loadTemplate.Execute(&out, m.CreateModel(dc)); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"Users", "/users/{userId}", "1.00%", "100.00", "1.5ms"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("load report page does not contain '%s'", expected)
+		}
+	}
+}
+
+func TestEndpointTemplateCurl(t *testing.T) {
+	dc := d.CreateDocContext()
+	dc.NewEndpointDocumentation("e1", "users", "Get user")
+	endpoint := dc.GetEndpoint()
+	endpoint.Method = "GET"
+	endpoint.UrlPath = "/users"
+	endpoint.Examples = []d.Example{{Method: "GET", Uri: "http://localhost/users", StatusCode: 200, Curl: "curl 'http://localhost/users'"}}
+	dc.SaveEndpointDocumentation()
+	var out bytes.Buffer
+	if err := endpointTemplate.Execute(&out, m.CreateModel(dc).FindEndpointById("e1")); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `<div class="example-curl"><pre>curl 'http://localhost/users'</pre></div>`) {
+		t.Errorf("endpoint details page does not contain curl command: %s", out.String())
+	}
+}